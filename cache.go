@@ -0,0 +1,240 @@
+package kktemplate
+
+import (
+	"sort"
+)
+
+const (
+	cacheHTML  = "html"
+	cacheFrame = "frame"
+	cacheText  = "text"
+
+	cacheFrameSet = "frameset"
+)
+
+// cacheEntry records what a cached template was built from, so a change to
+// any of its source templates can drop exactly the affected entries.
+type cacheEntry struct {
+	kind string
+	set  string
+	name string
+	lang string
+	deps []string
+}
+
+func (c cacheEntry) key() string {
+	return c.kind + ":" + c.mapName()
+}
+
+// mapName is the key of the entry in the template map of its kind.
+func (c cacheEntry) mapName() string {
+	if c.set != "" {
+		return c.set + ":" + c.name + "-" + c.lang
+	}
+	return c.name + "-" + c.lang
+}
+
+func (e *Engine) trackCache(entry cacheEntry) {
+	e.entryLocker.Lock()
+	defer e.entryLocker.Unlock()
+	if e.cacheEntries == nil {
+		e.cacheEntries = map[string]cacheEntry{}
+	}
+	e.cacheEntries[entry.key()] = entry
+}
+
+// invalidateNames drops every cached template that depends on one of names
+// and returns the dropped entries.
+func (e *Engine) invalidateNames(names map[string]bool) []cacheEntry {
+	e.entryLocker.Lock()
+	var dropped []cacheEntry
+	for key, entry := range e.cacheEntries {
+		if e.dependsOn(entry, names) {
+			dropped = append(dropped, entry)
+			delete(e.cacheEntries, key)
+		}
+	}
+	e.entryLocker.Unlock()
+
+	for _, entry := range dropped {
+		e.dropCache(entry)
+	}
+	return dropped
+}
+
+// dependsOn reports whether entry was built from one of names. Every entry
+// includes the partials, so a partial, including a new one, affects them all.
+func (e *Engine) dependsOn(entry cacheEntry, names map[string]bool) bool {
+	for name := range names {
+		if e.isPartial(name) {
+			return true
+		}
+	}
+	for _, dep := range entry.deps {
+		if names[dep] {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) dropCache(entry cacheEntry) {
+	mapName := entry.mapName()
+	switch entry.kind {
+	case cacheHTML:
+		e.htmlLocker.Lock()
+		dropTemplate(e.htmlTemplateCopies, *e.htmlTemplateMap, mapName)
+		e.htmlLocker.Unlock()
+	case cacheFrame:
+		e.htmlLocker.Lock()
+		dropTemplate(e.htmlTemplateCopies, *e.frameHtmlTemplateMap, mapName)
+		e.htmlLocker.Unlock()
+	case cacheFrameSet:
+		e.htmlLocker.Lock()
+		dropTemplate(e.htmlTemplateCopies, e.frameSetTemplateMap, mapName)
+		e.htmlLocker.Unlock()
+	case cacheText:
+		e.textLocker.Lock()
+		dropTemplate(e.textTemplateCopies, *e.textTemplateMap, mapName)
+		e.textLocker.Unlock()
+	}
+}
+
+// reloadCache parses entry again so the new version is cached right away.
+func (e *Engine) reloadCache(entry cacheEntry) error {
+	var err error
+	switch entry.kind {
+	case cacheHTML:
+		_, err = e.LoadHtml(entry.name, entry.lang)
+	case cacheFrame:
+		_, err = e.LoadFrameHtml(entry.name, entry.lang)
+	case cacheFrameSet:
+		_, err = e.LoadFrameSetHtml(entry.set, entry.name, entry.lang)
+	case cacheText:
+		_, err = e.LoadText(entry.name, entry.lang)
+	}
+	if err != nil {
+		return newLoadError(entry.name, entry.lang, err)
+	}
+	return nil
+}
+
+func Invalidate(name string, lang string) {
+	defaultEngine.Invalidate(name, lang)
+}
+
+// Invalidate drops the cached html, frame-composed and text templates of name
+// for lang, together with every cached template composed with it (e.g. all
+// pages, when name is a frame or a partial). An empty lang drops name for
+// every language.
+func (e *Engine) Invalidate(name string, lang string) {
+	if e == nil || e.htmlTemplateMap == nil || e.textTemplateMap == nil {
+		return
+	}
+
+	e.entryLocker.Lock()
+	var dropped []cacheEntry
+	for key, entry := range e.cacheEntries {
+		if lang != "" && entry.lang != lang {
+			continue
+		}
+		if e.dependsOn(entry, map[string]bool{name: true}) {
+			dropped = append(dropped, entry)
+			delete(e.cacheEntries, key)
+		}
+	}
+	e.entryLocker.Unlock()
+
+	for _, entry := range dropped {
+		e.dropCache(entry)
+	}
+	if lang != "" {
+		for _, kind := range []string{cacheHTML, cacheFrame, cacheText} {
+			e.dropCache(cacheEntry{kind: kind, name: name, lang: lang})
+		}
+	}
+
+	e.resetFrameChecks(map[string]bool{name: true})
+}
+
+func InvalidateAll() {
+	defaultEngine.InvalidateAll()
+}
+
+// InvalidateAll empties every template cache of the engine and resets the
+// frame existence check.
+func (e *Engine) InvalidateAll() {
+	if e == nil || e.htmlTemplateMap == nil || e.textTemplateMap == nil {
+		return
+	}
+
+	e.entryLocker.Lock()
+	clear(e.cacheEntries)
+	e.entryLocker.Unlock()
+
+	e.htmlLocker.Lock()
+	clear(*e.htmlTemplateMap)
+	clear(*e.frameHtmlTemplateMap)
+	clear(e.frameSetTemplateMap)
+	clear(e.htmlTemplateCopies)
+	e.htmlLocker.Unlock()
+
+	e.textLocker.Lock()
+	clear(*e.textTemplateMap)
+	clear(e.textTemplateCopies)
+	e.textLocker.Unlock()
+
+	e.ResetFrameCheck()
+}
+
+func CachedKeys() []string {
+	return defaultEngine.CachedKeys()
+}
+
+// CachedKeys lists the cached templates as sorted "<kind>:<name>-<lang>"
+// keys, where kind is html, frame or text, and frame set pages as
+// "frameset:<set>:<name>-<lang>".
+func (e *Engine) CachedKeys() []string {
+	if e == nil || e.htmlTemplateMap == nil || e.textTemplateMap == nil {
+		return nil
+	}
+
+	var keys []string
+	e.htmlLocker.Lock()
+	for mapName := range *e.htmlTemplateMap {
+		keys = append(keys, cacheHTML+":"+mapName)
+	}
+	for mapName := range *e.frameHtmlTemplateMap {
+		keys = append(keys, cacheFrame+":"+mapName)
+	}
+	for mapName := range e.frameSetTemplateMap {
+		keys = append(keys, cacheFrameSet+":"+mapName)
+	}
+	e.htmlLocker.Unlock()
+
+	e.textLocker.Lock()
+	for mapName := range *e.textTemplateMap {
+		keys = append(keys, cacheText+":"+mapName)
+	}
+	e.textLocker.Unlock()
+
+	sort.Strings(keys)
+	return keys
+}
+
+func ResetFrameCheck() {
+	defaultEngine.ResetFrameCheck()
+}
+
+// ResetFrameCheck forgets, for every language, that the frame templates and
+// the frames of every frame set were found, so the next load checks them
+// again.
+func (e *Engine) ResetFrameCheck() {
+	if e == nil || e.frameExist == nil || e.frameLocker == nil {
+		return
+	}
+	e.frameLocker.Lock()
+	clear(*e.frameExist)
+	clear(e.frameSetExist)
+	e.frameLocker.Unlock()
+}
@@ -0,0 +1,95 @@
+// cache_test.go contains unit tests for the cache invalidation and inspection API.
+//
+// Test Case Index:
+// - TestCachedKeys: CachedKeys lists every cached template by kind, name and language.
+// - TestInvalidate: Invalidate drops one name for one language, or for every language when lang is empty.
+// - TestInvalidate_Frame: invalidating a frame drops the pages composed with it and rechecks the frames.
+// - TestInvalidateAll: InvalidateAll empties the caches so the next load reparses from the source.
+package kktemplate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCachedKeys(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/hello.tmpl": "hello"})
+	if _, err := e.LoadHtml("hello", "en"); err != nil {
+		t.Fatalf("LoadHtml: %v", err)
+	}
+	if _, err := e.LoadText("hello", "ja"); err != nil {
+		t.Fatalf("LoadText: %v", err)
+	}
+
+	if got, want := e.CachedKeys(), []string{"html:hello-en", "text:hello-ja"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CachedKeys: got %v want %v", got, want)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/hello.tmpl": "hello", "default/other.tmpl": "other"})
+	for _, lang := range []string{"en", "ja"} {
+		for _, name := range []string{"hello", "other"} {
+			if _, err := e.LoadHtml(name, lang); err != nil {
+				t.Fatalf("LoadHtml: %v", err)
+			}
+		}
+	}
+
+	e.Invalidate("hello", "en")
+	if got, want := e.CachedKeys(), []string{"html:hello-ja", "html:other-en", "html:other-ja"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CachedKeys after Invalidate: got %v want %v", got, want)
+	}
+
+	e.Invalidate("other", "")
+	if got, want := e.CachedKeys(), []string{"html:hello-ja"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CachedKeys after Invalidate all langs: got %v want %v", got, want)
+	}
+}
+
+func TestInvalidate_Frame(t *testing.T) {
+	files := map[string]string{"default/page.tmpl": "page"}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = frame
+	}
+	e, fsys := newMapFSEngine(files)
+	if _, err := e.LoadFrameHtml("page", "en"); err != nil {
+		t.Fatalf("LoadFrameHtml: %v", err)
+	}
+	if _, err := e.LoadHtml("page", "en"); err != nil {
+		t.Fatalf("LoadHtml: %v", err)
+	}
+
+	delete(fsys, "default/_footer_claim.tmpl")
+	e.Invalidate("_footer_claim", "en")
+	if got, want := e.CachedKeys(), []string{"html:page-en"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CachedKeys: got %v want %v", got, want)
+	}
+	if _, err := e.LoadFrameHtml("page", "en"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected frames to be checked again, got %v", err)
+	}
+}
+
+func TestInvalidateAll(t *testing.T) {
+	fsys := fstest.MapFS{"default/hello.tmpl": {Data: []byte("v1")}}
+	e := New()
+	e.SetTemplateFS(fsys)
+	first, err := e.LoadText("hello", "en")
+	if err != nil {
+		t.Fatalf("LoadText: %v", err)
+	}
+
+	e.InvalidateAll()
+	if keys := e.CachedKeys(); len(keys) != 0 {
+		t.Fatalf("expected empty cache, got %v", keys)
+	}
+	second, err := e.LoadText("hello", "en")
+	if err != nil {
+		t.Fatalf("LoadText: %v", err)
+	}
+	if first == second {
+		t.Fatalf("expected a freshly parsed template")
+	}
+}
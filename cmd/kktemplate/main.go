@@ -0,0 +1,211 @@
+// Command kktemplate provides maintenance tools for kktemplate template trees.
+//
+// Usage:
+//
+//	kktemplate lint [-format text|json] [-funcs a,b] [-frames _main,...] [-partials dir] <template-root>
+//	kktemplate keys [-format text|json] [-translations dir] [-stub lang] <template-root>
+//
+// lint exits with status 1 when it finds at least one error-severity issue.
+// keys lists the translation keys used by the templates and, with
+// -translations, the keys each dictionary is missing or does not use; -stub
+// prints a kktranslation YAML file holding the keys lang is missing.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yetiz-org/goth-kktemplate"
+	"github.com/yetiz-org/goth-kktranslation"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	switch args[0] {
+	case "lint":
+		return runLint(args[1:], stdout, stderr)
+	case "keys":
+		return runKeys(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "kktemplate: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: kktemplate <command> [flags] <template-root>")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "commands:")
+	fmt.Fprintln(w, "  lint    validate a template tree the way kktemplate.Engine loads it")
+	fmt.Fprintln(w, "  keys    extract translation keys and diff them against kktranslation dictionaries")
+}
+
+// newEngine builds an engine over root with the flags shared by every
+// command.
+func newEngine(root string, frames string, partials string) *kktemplate.Engine {
+	engine := kktemplate.New()
+	engine.SetTemplateRootPath(root)
+	if frames != "" {
+		engine.SetStructTemplateFrames(splitList(frames))
+	}
+	engine.SetPartialsDir(partials)
+	return engine
+}
+
+func runLint(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "output format: text or json")
+	funcs := flags.String("funcs", "", "comma separated names of functions registered in FuncMap")
+	frames := flags.String("frames", "", "comma separated struct template frames (default: the kktemplate defaults)")
+	partials := flags.String("partials", "", "partials directory inside each language directory")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "kktemplate lint: expected exactly one template root")
+		return 2
+	}
+
+	issues, err := newEngine(flags.Arg(0), *frames, *partials).Lint(splitList(*funcs)...)
+	if err != nil {
+		fmt.Fprintf(stderr, "kktemplate lint: %v\n", err)
+		return 2
+	}
+
+	switch *format {
+	case "json":
+		if issues == nil {
+			issues = []kktemplate.LintIssue{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(issues); err != nil {
+			fmt.Fprintf(stderr, "kktemplate lint: %v\n", err)
+			return 2
+		}
+	case "text":
+		for _, issue := range issues {
+			fmt.Fprintln(stdout, issue.String())
+		}
+	default:
+		fmt.Fprintf(stderr, "kktemplate lint: unknown format %q\n", *format)
+		return 2
+	}
+
+	for _, issue := range issues {
+		if issue.Severity == kktemplate.LintError {
+			return 1
+		}
+	}
+	return 0
+}
+
+func runKeys(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("keys", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "output format: text or json")
+	translations := flags.String("translations", "", "kktranslation dictionary directory to diff against")
+	stub := flags.String("stub", "", "print a YAML stub with the keys this language is missing")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "kktemplate keys: expected exactly one template root")
+		return 2
+	}
+
+	engine := newEngine(flags.Arg(0), "", "")
+	keys, err := engine.TranslationKeys()
+	if err != nil {
+		fmt.Fprintf(stderr, "kktemplate keys: %v\n", err)
+		return 2
+	}
+
+	var reports []kktemplate.TranslationReport
+	if *translations != "" {
+		kktranslation.LangRootPath = *translations
+		if reports, err = engine.CheckTranslations(); err != nil {
+			fmt.Fprintf(stderr, "kktemplate keys: %v\n", err)
+			return 2
+		}
+	}
+
+	if *stub != "" {
+		missing := keys
+		for _, report := range reports {
+			if strings.EqualFold(report.Lang, *stub) {
+				missing = report.Missing
+			}
+		}
+		data, err := kktemplate.TranslationStub(*stub, missing)
+		if err != nil {
+			fmt.Fprintf(stderr, "kktemplate keys: %v\n", err)
+			return 2
+		}
+		stdout.Write(data)
+		return 0
+	}
+
+	switch *format {
+	case "json":
+		if keys == nil {
+			keys = []kktemplate.TranslationKey{}
+		}
+		if reports == nil {
+			reports = []kktemplate.TranslationReport{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(struct {
+			Keys    []kktemplate.TranslationKey    `json:"keys"`
+			Reports []kktemplate.TranslationReport `json:"reports"`
+		}{keys, reports})
+		if err != nil {
+			fmt.Fprintf(stderr, "kktemplate keys: %v\n", err)
+			return 2
+		}
+	case "text":
+		for _, key := range keys {
+			fmt.Fprintf(stdout, "%s:%d: %s\n", key.File, key.Line, key.Key)
+		}
+		for _, report := range reports {
+			for _, key := range report.Missing {
+				fmt.Fprintf(stdout, "%s:%d: missing in %s: %s\n", key.File, key.Line, report.Lang, key.Key)
+			}
+			for _, key := range report.Unused {
+				fmt.Fprintf(stdout, "unused in %s: %s\n", report.Lang, key)
+			}
+		}
+	default:
+		fmt.Fprintf(stderr, "kktemplate keys: unknown format %q\n", *format)
+		return 2
+	}
+	return 0
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestRunLint_Text(t *testing.T) {
+	root := t.TempDir()
+	writeTemplate(t, root, "default/page.tmpl", "{{missing}}")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"lint", "-frames", "_main", root}, &stdout, &stderr); code != 1 {
+		t.Fatalf("exit code: got %d, stderr %q", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "default/page.tmpl:1: error: [undefined-func]") {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+}
+
+func TestRunLint_JSON(t *testing.T) {
+	root := t.TempDir()
+	writeTemplate(t, root, "default/_main.tmpl", "main")
+	writeTemplate(t, root, "default/page.tmpl", "{{helper}}")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"lint", "-format", "json", "-frames", "_main", "-funcs", "helper", root}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code: got %d, stderr %q", code, stderr.String())
+	}
+	var issues []map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &issues); err != nil {
+		t.Fatalf("decode output %q: %v", stdout.String(), err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestRunKeys_Stub(t *testing.T) {
+	root := t.TempDir()
+	writeTemplate(t, root, "default/page.tmpl", "{{T \"title\"}}{{T \"intro\"}}")
+	translations := t.TempDir()
+	writeTemplate(t, translations, "en.yaml", "version: \"1\"\nlang: \"en\"\nname: \"English\"\ndict:\n  title: \"Title\"\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"keys", "-translations", translations, "-stub", "en", root}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code: got %d, stderr %q", code, stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, "intro: intro") || strings.Contains(out, "title:") {
+		t.Fatalf("unexpected stub: %q", out)
+	}
+}
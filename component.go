@@ -0,0 +1,163 @@
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	html "html/template"
+	"io"
+	text "text/template"
+)
+
+// componentFuncs are the functions bound to the template being loaded, so
+// they can execute the other templates of its set.
+var componentFuncs = []string{"component", "render"}
+
+// componentPlaceholders declares componentFuncs at parse time; the loaders
+// replace them once the template is parsed.
+func componentPlaceholders() map[string]any {
+	unbound := func(string, ...any) (string, error) {
+		return "", fmt.Errorf("kktemplate: component functions are not bound")
+	}
+	return map[string]any{"component": unbound, "render": unbound}
+}
+
+// componentData builds the data of a component call from key/value pairs.
+// The slot body of a component is passed like any value, under "children".
+func componentData(args []any) (map[string]any, error) {
+	data := map[string]any{}
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("component argument %d: key must be a string, got %T", i, args[i])
+		}
+		if i+1 == len(args) {
+			return nil, fmt.Errorf("component argument %d: key %q has no value", i, key)
+		}
+		data[key] = args[i+1]
+	}
+	return data, nil
+}
+
+// ErrComponentDepth is returned when component and render calls nest deeper
+// than maxComponentDepth, e.g. a component that renders itself.
+var ErrComponentDepth = fmt.Errorf("component nesting too deep")
+
+const maxComponentDepth = 100
+
+// componentState belongs to one execution of a template set: the cached
+// template or one of its copies while in use.
+type componentState struct {
+	// depth counts the component and render calls the execution is nested
+	// in.
+	depth int
+	// funcs are the request function overrides of the render, and reset the
+	// placeholders that restore them.
+	funcs map[string]any
+	reset map[string]any
+}
+
+type templateExecutor interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+// runComponent executes the template name of a set into a string. Components
+// nest through ExecuteTemplate, which starts text/template's own depth check
+// over, so each call runs on a copy of the set one level deeper than the
+// execution making it and fails past maxComponentDepth instead of overflowing
+// the stack.
+func runComponent[T templateExecutor](copies *templateCopies[T], state *componentState, name string, data any) (string, error) {
+	if state.depth >= maxComponentDepth {
+		return "", fmt.Errorf("component %q: %w", name, ErrComponentDepth)
+	}
+	nested, err := copies.get(state.depth+1, state.funcs, state.reset)
+	if err != nil {
+		return "", err
+	}
+	defer copies.put(nested)
+
+	var buf bytes.Buffer
+	if err := nested.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		// Every level wraps the error again; keep it short.
+		if errors.Is(err, ErrComponentDepth) {
+			return "", fmt.Errorf("component %q: %w", name, ErrComponentDepth)
+		}
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// componentName resolves a component to a template of the set: the partial
+// of that name when it exists, otherwise a template defined as name.
+func (e *Engine) componentName(name string, lookup func(string) bool) string {
+	if dir := e.partialsDirValue(); dir != "" && lookup(dir+"/"+name) {
+		return dir + "/" + name
+	}
+	return name
+}
+
+// userDefinesFunc reports whether name is provided by the FuncMap, a factory
+// or a request function, which take precedence over the built-in helpers.
+func (e *Engine) userDefinesFunc(name string) bool {
+	if _, ok := e.funcMapValue()[name]; ok {
+		return true
+	}
+	e.factoryLocker.RLock()
+	defer e.factoryLocker.RUnlock()
+	_, factory := e.funcFactories[name]
+	_, request := e.requestFuncs[name]
+	return factory || request
+}
+
+func (e *Engine) componentFuncMap(lookup func(string) bool, run func(name string, data any) (string, error)) map[string]any {
+	funcs := map[string]any{
+		"component": func(name string, args ...any) (string, error) {
+			data, err := componentData(args)
+			if err != nil {
+				return "", err
+			}
+			return run(e.componentName(name, lookup), data)
+		},
+		"render": func(name string, data ...any) (string, error) {
+			if len(data) > 1 {
+				return "", fmt.Errorf("render %q: at most one data argument, got %d", name, len(data))
+			}
+			var arg any
+			if len(data) == 1 {
+				arg = data[0]
+			}
+			return run(name, arg)
+		},
+	}
+	for _, name := range componentFuncs {
+		if e.userDefinesFunc(name) {
+			delete(funcs, name)
+		}
+	}
+	return funcs
+}
+
+// bindHTMLComponents binds component and render to t, the template of the
+// execution state, running nested calls on copies. They return html.HTML, as
+// the output of an html template is already escaped.
+func (e *Engine) bindHTMLComponents(t *html.Template, state *componentState, copies *htmlCopies) *html.Template {
+	run := func(name string, data any) (string, error) {
+		return runComponent(copies, state, name, data)
+	}
+	funcs := html.FuncMap{}
+	for name, fn := range e.componentFuncMap(func(name string) bool { return t.Lookup(name) != nil }, run) {
+		fn := fn.(func(string, ...any) (string, error))
+		funcs[name] = func(name string, args ...any) (html.HTML, error) {
+			out, err := fn(name, args...)
+			return html.HTML(out), err
+		}
+	}
+	return t.Funcs(funcs)
+}
+
+func (e *Engine) bindTextComponents(t *text.Template, state *componentState, copies *textCopies) *text.Template {
+	run := func(name string, data any) (string, error) {
+		return runComponent(copies, state, name, data)
+	}
+	return t.Funcs(e.componentFuncMap(func(name string) bool { return t.Lookup(name) != nil }, run))
+}
@@ -0,0 +1,145 @@
+// component_test.go contains unit tests for the component and render helpers.
+//
+// Test Case Index:
+// - TestComponent_NamedArgs: component renders a partial with named arguments and an explicit children slot, escaped once.
+// - TestComponent_Define: component falls back to a template defined under the component name.
+// - TestComponent_Text: the text loader binds component and render returning plain strings.
+// - TestComponent_RequestFuncs: components see request-scoped function overrides.
+// - TestComponent_Errors: bad arguments, keys without a value, unknown and endlessly nested components fail the execution.
+// - TestComponent_DepthPerRender: concurrent renders nested just below the limit each succeed, as depth is counted per execution.
+// - TestComponent_FuncMapWins: FuncMap entries named like the helpers take precedence.
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	html "html/template"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestComponent_NamedArgs(t *testing.T) {
+	e, _ := newPartialsEngine(map[string]string{
+		"default/partials/card.tmpl": "<div><h2>{{.title}}</h2>{{.children}}</div>",
+		"default/page.tmpl":          "{{component \"card\" \"title\" .T \"children\" (render \"card-body\" .)}}{{define \"card-body\"}}<p>{{.B}}</p>{{end}}",
+	})
+
+	var buf bytes.Buffer
+	data := map[string]string{"T": "<Title>", "B": "a&b"}
+	if err := e.RenderHtml(&buf, "page", "en", data); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if got, want := buf.String(), "<div><h2>&lt;Title&gt;</h2><p>a&amp;b</p></div>"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestComponent_Define(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/page.tmpl": "{{component \"badge\" \"label\" \"new\" \"children\" \"<i>\"}}{{define \"badge\"}}[{{.label}}|{{.children}}]{{end}}",
+	})
+
+	var buf bytes.Buffer
+	if err := e.RenderHtml(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if got, want := buf.String(), "[new|&lt;i&gt;]"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestComponent_Text(t *testing.T) {
+	e, _ := newPartialsEngine(map[string]string{
+		"default/partials/line.tmpl": "{{.key}}={{.children}}",
+		"default/mail.tmpl":          "{{component \"line\" \"key\" \"to\" \"children\" .}};{{render \"sign\"}}{{define \"sign\"}}<team>{{end}}",
+	})
+
+	var buf bytes.Buffer
+	if err := e.RenderText(&buf, "mail", "en", "<ann>"); err != nil {
+		t.Fatalf("RenderText: %v", err)
+	}
+	if got, want := buf.String(), "to=<ann>;<team>"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestComponent_RequestFuncs(t *testing.T) {
+	e, _ := newPartialsEngine(map[string]string{
+		"default/partials/form.tmpl": "<form>{{csrfField}}</form>",
+		"default/page.tmpl":          "{{component \"form\"}}",
+	})
+	e.DeclareRequestFunc("csrfField", func() html.HTML { return "" })
+
+	var buf bytes.Buffer
+	field := func() html.HTML { return `<input name="csrf">` }
+	if err := e.RenderHtml(&buf, "page", "en", nil, WithFuncs(map[string]any{"csrfField": field})); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if got, want := buf.String(), `<form><input name="csrf"></form>`; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestComponent_Errors(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/badkey.tmpl":  "{{component \"x\" 1 2}}{{define \"x\"}}x{{end}}",
+		"default/missing.tmpl": "{{component \"nope\"}}",
+		"default/novalue.tmpl": "{{component \"x\" \"title\"}}{{define \"x\"}}{{.title}}{{end}}",
+		"default/loop.tmpl":    "{{component \"self\"}}{{define \"self\"}}{{component \"self\"}}{{end}}",
+		"default/loop2.tmpl":   "{{render \"a\"}}{{define \"a\"}}{{render \"b\"}}{{end}}{{define \"b\"}}{{render \"a\"}}{{end}}",
+	})
+
+	for _, name := range []string{"badkey", "missing", "novalue", "loop", "loop2"} {
+		var buf bytes.Buffer
+		err := e.RenderHtml(&buf, name, "en", nil)
+		if !errors.Is(err, ErrTemplateExecute) {
+			t.Fatalf("%s: expected ErrTemplateExecute, got %v", name, err)
+		}
+		if strings.HasPrefix(name, "loop") && !errors.Is(err, ErrComponentDepth) {
+			t.Fatalf("%s: expected ErrComponentDepth, got %v", name, err)
+		}
+	}
+}
+
+func TestComponent_DepthPerRender(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/tree.tmpl": "{{render \"node\" .}}{{define \"node\"}}{{if .}}[{{render \"node\" (slice . 1)}}]{{end}}{{end}}",
+	})
+	levels := make([]int, maxComponentDepth-1)
+	want := strings.Repeat("[", len(levels)) + strings.Repeat("]", len(levels))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			if err := e.RenderHtml(&buf, "tree", "en", levels); err != nil {
+				errs <- err
+			} else if buf.String() != want {
+				errs <- fmt.Errorf("got %q", buf.String())
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+}
+
+func TestComponent_FuncMapWins(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{render \"x\"}}"})
+	e.SetFuncMap(html.FuncMap{"render": func(s string) string { return "custom " + s }})
+
+	var buf bytes.Buffer
+	if err := e.RenderHtml(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if got, want := buf.String(), "custom x"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package kktemplate
+
+import (
+	html "html/template"
+	"sync"
+	text "text/template"
+)
+
+// maxFreeCopies bounds the idle copies kept for one cached template.
+const maxFreeCopies = 32
+
+// templateCopies hands out copies of a cached template, for the renders that
+// rebind its request functions and for the component calls nested in its
+// executions. html/template cannot clone a template once it has been
+// executed, so the copies are cloned from a prototype taken when the template
+// is cached, and are exactly that version of it. A copy is given back after
+// use and kept, so its escaping is done once; the copies are dropped together
+// with the cache entry.
+type templateCopies[T any] struct {
+	clone func() (*templateCopy[T], error)
+	funcs func(T, map[string]any)
+
+	mu   sync.Mutex
+	free []*templateCopy[T]
+}
+
+type templateCopy[T any] struct {
+	tmpl  T
+	state *componentState
+}
+
+type htmlCopies = templateCopies[*html.Template]
+type textCopies = templateCopies[*text.Template]
+
+// newHTMLCopies takes the prototype of t, which must not have been executed,
+// and binds the component functions of t to the returned copies.
+func (e *Engine) newHTMLCopies(t *html.Template) (*htmlCopies, error) {
+	prototype, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	copies := &htmlCopies{
+		funcs: func(t *html.Template, funcs map[string]any) { t.Funcs(funcs) },
+	}
+	copies.clone = func() (*templateCopy[*html.Template], error) {
+		clone, err := prototype.Clone()
+		if err != nil {
+			return nil, err
+		}
+		state := &componentState{}
+		return &templateCopy[*html.Template]{tmpl: e.bindHTMLComponents(clone, state, copies), state: state}, nil
+	}
+	e.bindHTMLComponents(t, &componentState{}, copies)
+	return copies, nil
+}
+
+// newTextCopies is newHTMLCopies for text templates.
+func (e *Engine) newTextCopies(t *text.Template) (*textCopies, error) {
+	prototype, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	copies := &textCopies{
+		funcs: func(t *text.Template, funcs map[string]any) { t.Funcs(funcs) },
+	}
+	copies.clone = func() (*templateCopy[*text.Template], error) {
+		clone, err := prototype.Clone()
+		if err != nil {
+			return nil, err
+		}
+		state := &componentState{}
+		return &templateCopy[*text.Template]{tmpl: e.bindTextComponents(clone, state, copies), state: state}, nil
+	}
+	e.bindTextComponents(t, &componentState{}, copies)
+	return copies, nil
+}
+
+// get returns an idle copy, or a new one, for an execution at depth bound to
+// funcs, restored by reset.
+func (c *templateCopies[T]) get(depth int, funcs map[string]any, reset map[string]any) (*templateCopy[T], error) {
+	c.mu.Lock()
+	var copied *templateCopy[T]
+	if n := len(c.free); n > 0 {
+		copied = c.free[n-1]
+		c.free = c.free[:n-1]
+	}
+	c.mu.Unlock()
+
+	if copied == nil {
+		var err error
+		if copied, err = c.clone(); err != nil {
+			return nil, err
+		}
+	}
+	copied.state.depth = depth
+	if len(funcs) > 0 {
+		c.funcs(copied.tmpl, funcs)
+		copied.state.funcs, copied.state.reset = funcs, reset
+	}
+	return copied, nil
+}
+
+// put restores copied and keeps it for the next execution.
+func (c *templateCopies[T]) put(copied *templateCopy[T]) {
+	if copied.state.funcs != nil {
+		c.funcs(copied.tmpl, copied.state.reset)
+		copied.state.funcs, copied.state.reset = nil, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.free) < maxFreeCopies {
+		c.free = append(c.free, copied)
+	}
+}
+
+// cachedTemplate returns the template cached in templates under mapName
+// with its copies from registry, or a nil copies when there is none. The lock
+// of templates must be held.
+func cachedTemplate[T comparable](registry map[T]*templateCopies[T], templates map[string]T, mapName string) (T, *templateCopies[T]) {
+	tmpl := templates[mapName]
+	return tmpl, registry[tmpl]
+}
+
+// storeTemplate caches parsed with its copies under mapName, unless another
+// load cached the template first, and returns the cached template and copies
+// and whether parsed was stored. The lock of templates must be held.
+func storeTemplate[T comparable](registry *map[T]*templateCopies[T], templates map[string]T, mapName string, parsed T, copies *templateCopies[T]) (T, *templateCopies[T], bool) {
+	if tmpl, existing := cachedTemplate(*registry, templates, mapName); existing != nil {
+		return tmpl, existing, false
+	}
+	if *registry == nil {
+		*registry = map[T]*templateCopies[T]{}
+	}
+	delete(*registry, templates[mapName])
+	templates[mapName] = parsed
+	(*registry)[parsed] = copies
+	return parsed, copies, true
+}
+
+// dropTemplate removes mapName and its copies. The lock of templates must be
+// held.
+func dropTemplate[T comparable](registry map[T]*templateCopies[T], templates map[string]T, mapName string) {
+	delete(registry, templates[mapName])
+	delete(templates, mapName)
+}
@@ -0,0 +1,173 @@
+package kktemplate
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// EmailHeader holds the header fields of a rendered email. Date defaults to
+// the render time; Extra adds other fields, e.g. "List-Unsubscribe", and may
+// also set "Message-ID", which is generated otherwise.
+type EmailHeader struct {
+	From    *mail.Address
+	To      []*mail.Address
+	Cc      []*mail.Address
+	ReplyTo []*mail.Address
+	Date    time.Time
+	Extra   map[string]string
+}
+
+// EmailFrames picks the frames RenderEmail composes the html part with: the
+// frame set set, registered with RegisterFrameSet, or no frames when set is
+// "". Without it the struct template frames are used when there are any.
+func EmailFrames(set string) RenderOption {
+	return func(config *renderConfig) {
+		config.emailFrames = &set
+	}
+}
+
+func RenderEmail(w io.Writer, name string, lang string, header EmailHeader, data any, opts ...RenderOption) error {
+	return defaultEngine.RenderEmail(w, name, lang, header, data, opts...)
+}
+
+// RenderEmail renders the text templates "<name>.subject" and "<name>.text"
+// and the html template "<name>.html", composed with the frames chosen by
+// EmailFrames or else the struct template frames when there are any, with the
+// same data, and writes them to w as a
+// multipart/alternative MIME message ready for an SMTP client. Non-ASCII
+// header values are encoded as RFC 2047 encoded words. Nothing is written
+// when a template fails.
+func (e *Engine) RenderEmail(w io.Writer, name string, lang string, header EmailHeader, data any, opts ...RenderOption) error {
+	var subject, plain, page bytes.Buffer
+	if err := e.RenderText(&subject, name+".subject", lang, data, opts...); err != nil {
+		return err
+	}
+	if err := e.RenderText(&plain, name+".text", lang, data, opts...); err != nil {
+		return err
+	}
+	htmlName := name + ".html"
+	frames := newRenderConfig(opts).emailFrames
+	var err error
+	switch {
+	case frames != nil && *frames != "":
+		err = e.RenderFrameSet(&page, *frames, htmlName, lang, data, opts...)
+	case frames == nil && len(e.structTemplateFramesValue()) > 0:
+		err = e.RenderFrame(&page, htmlName, lang, data, opts...)
+	default:
+		err = e.RenderHtml(&page, htmlName, lang, data, opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	body := multipart.NewWriter(&msg)
+	writeEmailHeader(&msg, header, strings.Join(strings.Fields(subject.String()), " "), body.Boundary())
+	if err := writeEmailPart(body, "text/plain; charset=utf-8", plain.Bytes()); err != nil {
+		return err
+	}
+	if err := writeEmailPart(body, "text/html; charset=utf-8", page.Bytes()); err != nil {
+		return err
+	}
+	if err := body.Close(); err != nil {
+		return err
+	}
+	_, err = msg.WriteTo(w)
+	return err
+}
+
+func writeEmailHeader(w io.Writer, header EmailHeader, subject string, boundary string) {
+	date := header.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	fields := map[string]string{}
+	for key, value := range header.Extra {
+		fields[textproto.CanonicalMIMEHeaderKey(headerValue(key))] = mime.QEncoding.Encode("utf-8", headerValue(value))
+	}
+	if header.From != nil {
+		fields["From"] = header.From.String()
+	}
+	setAddressField(fields, "To", header.To)
+	setAddressField(fields, "Cc", header.Cc)
+	setAddressField(fields, "Reply-To", header.ReplyTo)
+	fields["Subject"] = mime.QEncoding.Encode("utf-8", subject)
+	fields["Date"] = date.Format(time.RFC1123Z)
+	if _, ok := fields["Message-Id"]; !ok {
+		fields["Message-Id"] = newMessageID(header.From)
+	}
+	fields["Mime-Version"] = "1.0"
+	fields["Content-Type"] = mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": boundary})
+
+	for _, key := range sortedKeys(fields) {
+		fmt.Fprintf(w, "%s: %s\r\n", emailFieldName(key), fields[key])
+	}
+	io.WriteString(w, "\r\n")
+}
+
+func setAddressField(fields map[string]string, key string, addresses []*mail.Address) {
+	var list []string
+	for _, address := range addresses {
+		if address != nil {
+			list = append(list, address.String())
+		}
+	}
+	if len(list) > 0 {
+		fields[key] = strings.Join(list, ", ")
+	}
+}
+
+func writeEmailPart(w *multipart.Writer, contentType string, content []byte) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write(content); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// headerValue drops line breaks, so template data cannot inject header
+// fields.
+func headerValue(value string) string {
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool { return r == '\r' || r == '\n' }), " ")
+}
+
+// emailFieldName spells the canonical keys the way RFC 5322 and RFC 2045 do.
+func emailFieldName(key string) string {
+	switch key {
+	case "Message-Id":
+		return "Message-ID"
+	case "Mime-Version":
+		return "MIME-Version"
+	}
+	return key
+}
+
+func newMessageID(from *mail.Address) string {
+	domain := "localhost"
+	if from != nil {
+		if at := strings.LastIndex(from.Address, "@"); at >= 0 && at < len(from.Address)-1 {
+			domain = from.Address[at+1:]
+		}
+	}
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">"
+}
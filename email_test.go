@@ -0,0 +1,186 @@
+// email_test.go contains unit tests for the multipart email renderer.
+//
+// Test Case Index:
+// - TestRenderEmail_Multipart: RenderEmail writes a parseable multipart/alternative message with the subject, text and html parts.
+// - TestRenderEmail_EncodedHeaders: non-ASCII subjects and names are RFC 2047 encoded and line breaks cannot inject fields.
+// - TestRenderEmail_MissingPart: a missing variant fails the render without writing anything.
+// - TestRenderEmail_Frames: EmailFrames composes the html part with a frame set, or with no frames on an engine without frame files.
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEmailEngine() *Engine {
+	return newHTTPEngine(map[string]string{
+		"default/welcome.subject.tmpl": "Welcome {{.}}\n",
+		"default/welcome.text.tmpl":    "Hello {{.}}",
+		"default/welcome.html.tmpl":    "<p>Hello {{.}}</p>",
+		"zh/welcome.subject.tmpl":      "歡迎 {{.}}\n",
+	})
+}
+
+func readEmailParts(t *testing.T, msg *mail.Message) map[string]string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected Content-Type %q: %v", msg.Header.Get("Content-Type"), err)
+	}
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		parts[part.Header.Get("Content-Type")] = string(content)
+	}
+}
+
+func TestRenderEmail_Multipart(t *testing.T) {
+	e := newEmailEngine()
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	header := EmailHeader{
+		From: &mail.Address{Address: "noreply@example.com"},
+		To:   []*mail.Address{{Name: "Ann", Address: "ann@example.org"}, {Address: "bob@example.org"}},
+		Date: date,
+	}
+	if err := e.RenderEmail(&buf, "welcome", "en", header, "a&b"); err != nil {
+		t.Fatalf("RenderEmail: %v", err)
+	}
+	if !strings.Contains(buf.String(), "\r\nMIME-Version: 1.0\r\n") {
+		t.Fatalf("missing MIME-Version in %q", buf.String())
+	}
+
+	msg, err := mail.ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if got := msg.Header.Get("Subject"); got != "Welcome a&b" {
+		t.Fatalf("unexpected Subject %q", got)
+	}
+	if got, err := msg.Header.Date(); err != nil || !got.Equal(date) {
+		t.Fatalf("unexpected Date %v: %v", got, err)
+	}
+	to, err := msg.Header.AddressList("To")
+	if err != nil || len(to) != 2 || to[0].Name != "Ann" || to[1].Address != "bob@example.org" {
+		t.Fatalf("unexpected To %v: %v", to, err)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Fatalf("unexpected Message-ID %q", id)
+	}
+
+	parts := readEmailParts(t, msg)
+	if got := parts["text/plain; charset=utf-8"]; got != "Hello a&b" {
+		t.Fatalf("unexpected text part %q", got)
+	}
+	if got := parts["text/html; charset=utf-8"]; got != "<p>Hello a&amp;b</p>" {
+		t.Fatalf("unexpected html part %q", got)
+	}
+}
+
+func TestRenderEmail_EncodedHeaders(t *testing.T) {
+	e := newEmailEngine()
+
+	var buf bytes.Buffer
+	header := EmailHeader{
+		From:  &mail.Address{Name: "客服", Address: "support@example.com"},
+		To:    []*mail.Address{{Address: "ann@example.org"}},
+		Extra: map[string]string{"X-Campaign": "spring\r\nBcc: evil@example.net"},
+	}
+	if err := e.RenderEmail(&buf, "welcome", "zh", header, "安"); err != nil {
+		t.Fatalf("RenderEmail: %v", err)
+	}
+	raw := buf.String()
+	head := raw[:strings.Index(raw, "\r\n\r\n")]
+	for _, r := range head {
+		if r > 127 {
+			t.Fatalf("raw non-ASCII header %q", head)
+		}
+	}
+	if strings.Contains(head, "\r\nBcc:") {
+		t.Fatalf("header injected: %q", head)
+	}
+
+	msg, err := mail.ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "歡迎 安" {
+		t.Fatalf("unexpected Subject %q: %v", subject, err)
+	}
+	from, err := msg.Header.AddressList("From")
+	if err != nil || from[0].Name != "客服" {
+		t.Fatalf("unexpected From %v: %v", from, err)
+	}
+	if got := msg.Header.Get("X-Campaign"); got != "spring Bcc: evil@example.net" {
+		t.Fatalf("unexpected X-Campaign %q", got)
+	}
+}
+
+func TestRenderEmail_MissingPart(t *testing.T) {
+	e := newHTTPEngine(map[string]string{
+		"default/notice.subject.tmpl": "Notice",
+		"default/notice.text.tmpl":    "text",
+	})
+
+	var buf bytes.Buffer
+	err := e.RenderEmail(&buf, "notice", "en", EmailHeader{}, nil)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected ErrTemplateNotFound, got %v", err)
+	}
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) || renderErr.Name != "notice.html" {
+		t.Fatalf("unexpected error value %#v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("partial output written: %q", buf.String())
+	}
+}
+
+func TestRenderEmail_Frames(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/welcome.subject.tmpl": "Welcome",
+		"default/welcome.text.tmpl":    "Hello {{.}}",
+		"default/welcome.html.tmpl":    "{{block \"_mail\" .}}<p>Hello {{.}}</p>{{end}}",
+		"default/_mail.tmpl":           "<div class=\"mail\">Hi {{.}}</div>",
+	})
+	e.RegisterFrameSet("mail", []string{"_mail"})
+
+	if err := e.RenderEmail(io.Discard, "welcome", "en", EmailHeader{}, "ann"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected the missing struct frames to fail, got %v", err)
+	}
+
+	cases := map[string]string{"": "<p>Hello ann</p>", "mail": "<div class=\"mail\">Hi ann</div>"}
+	for set, want := range cases {
+		var buf bytes.Buffer
+		if err := e.RenderEmail(&buf, "welcome", "en", EmailHeader{}, "ann", EmailFrames(set)); err != nil {
+			t.Fatalf("RenderEmail(EmailFrames(%q)): %v", set, err)
+		}
+		msg, err := mail.ReadMessage(&buf)
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if got := readEmailParts(t, msg)["text/html; charset=utf-8"]; got != want {
+			t.Fatalf("EmailFrames(%q): got html part %q want %q", set, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package kktemplate
+
+import (
+	"strings"
+)
+
+// LangFallbackFunc returns the ordered list of language directories tried
+// when resolving a template for lang. Duplicates are ignored.
+type LangFallbackFunc func(lang string) []string
+
+// DefaultLangFallback is the built-in chain: lang, its base language (the
+// part before the first "-") and default.
+func DefaultLangFallback(lang string) []string {
+	if slang := strings.Split(lang, "-"); len(slang) > 1 {
+		return []string{lang, slang[0], "default"}
+	}
+	return []string{lang, "default"}
+}
+
+// LangFallbackMap builds a LangFallbackFunc from a declarative table such as
+//
+//	{"zh-HK": {"zh-TW", "zh-Hant", "en"}, "pt-BR": {"pt-PT"}}
+//
+// Keys are matched case-insensitively and their chain is lang followed by the
+// listed tags and default; tags missing from the table use DefaultLangFallback.
+func LangFallbackMap(table map[string][]string) LangFallbackFunc {
+	chains := make(map[string][]string, len(table))
+	for tag, fallbacks := range table {
+		chains[strings.ToLower(tag)] = append([]string(nil), fallbacks...)
+	}
+
+	return func(lang string) []string {
+		fallbacks, ok := chains[strings.ToLower(lang)]
+		if !ok {
+			return DefaultLangFallback(lang)
+		}
+		chain := make([]string, 0, len(fallbacks)+2)
+		chain = append(chain, lang)
+		chain = append(chain, fallbacks...)
+		return append(chain, "default")
+	}
+}
+
+// SetLangFallback replaces the language fallback chain used by every loader.
+// A nil fn restores DefaultLangFallback.
+func (e *Engine) SetLangFallback(fn LangFallbackFunc) {
+	if e == nil {
+		return
+	}
+	if e.setLangFallback != nil {
+		e.setLangFallback(fn)
+		return
+	}
+	e.langFallback = fn
+}
+
+// SetLangFallbackMap is SetLangFallback with LangFallbackMap(table).
+func (e *Engine) SetLangFallbackMap(table map[string][]string) {
+	e.SetLangFallback(LangFallbackMap(table))
+}
+
+func (e *Engine) langFallbackValue() LangFallbackFunc {
+	if e == nil {
+		return nil
+	}
+	if e.getLangFallback != nil {
+		return e.getLangFallback()
+	}
+	return e.langFallback
+}
+
+func LangChain(lang string) []string {
+	return defaultEngine.LangChain(lang)
+}
+
+// LangChain returns the language directories tried for lang, in order.
+func (e *Engine) LangChain(lang string) []string {
+	fn := e.langFallbackValue()
+	if fn == nil {
+		fn = DefaultLangFallback
+	}
+
+	chain := fn(lang)
+	out := make([]string, 0, len(chain))
+	seen := make(map[string]bool, len(chain))
+	for _, candidate := range chain {
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		out = append(out, candidate)
+	}
+	return out
+}
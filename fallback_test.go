@@ -0,0 +1,103 @@
+// fallback_test.go contains unit tests for the configurable language fallback chain.
+//
+// Test Case Index:
+// - TestLangChain_Default: the built-in chain is lang -> base language -> default.
+// - TestLangChain_Map: a declarative table yields lang -> listed tags -> default and leaves other tags untouched.
+// - TestEngine_SetLangFallback: every loader and the negotiation walk the configured chain.
+// - TestLangFallback_DefaultEngine: assigning LangFallback changes the package-level loaders.
+package kktemplate
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLangChain_Default(t *testing.T) {
+	e := New()
+	if got, want := e.LangChain("zh-TW"), []string{"zh-TW", "zh", "default"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("LangChain: got %v want %v", got, want)
+	}
+	if got, want := e.LangChain("en"), []string{"en", "default"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("LangChain: got %v want %v", got, want)
+	}
+}
+
+func TestLangChain_Map(t *testing.T) {
+	e := New()
+	e.SetLangFallbackMap(map[string][]string{
+		"zh-HK": {"zh-TW", "zh-Hant", "en", "default"},
+		"pt-BR": {"pt-PT"},
+	})
+
+	if got, want := e.LangChain("zh-hk"), []string{"zh-hk", "zh-TW", "zh-Hant", "en", "default"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("LangChain(zh-hk): got %v want %v", got, want)
+	}
+	if got, want := e.LangChain("pt-BR"), []string{"pt-BR", "pt-PT", "default"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("LangChain(pt-BR): got %v want %v", got, want)
+	}
+	if got, want := e.LangChain("ja-JP"), []string{"ja-JP", "ja", "default"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("LangChain(ja-JP): got %v want %v", got, want)
+	}
+}
+
+func TestEngine_SetLangFallback(t *testing.T) {
+	files := map[string]string{
+		"default/hello.tmpl": "default",
+		"zh-Hant/hello.tmpl": "hant",
+		"pt-PT/hello.tmpl":   "pt",
+	}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = frame
+	}
+	e, _ := newMapFSEngine(files)
+	e.SetLangFallback(func(lang string) []string {
+		if strings.HasPrefix(lang, "zh") {
+			return []string{lang, "zh-TW", "zh-Hant", "default"}
+		}
+		return LangFallbackMap(map[string][]string{"pt-BR": {"pt-PT"}})(lang)
+	})
+
+	for lang, want := range map[string]string{"zh-HK": "hant", "pt-BR": "pt", "pt": "default"} {
+		var buf bytes.Buffer
+		if err := e.RenderText(&buf, "hello", lang, nil); err != nil {
+			t.Fatalf("RenderText(%s): %v", lang, err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("RenderText(%s): got %q want %q", lang, got, want)
+		}
+		buf.Reset()
+		if err := e.RenderFrame(&buf, "hello", lang, nil); err != nil {
+			t.Fatalf("RenderFrame(%s): %v", lang, err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("RenderFrame(%s): got %q want %q", lang, got, want)
+		}
+	}
+
+	if dir, lang := e.NegotiateLang("hello", "fr,zh-HK;q=0.5"); dir != "zh-Hant" || lang != "zh-hk" {
+		t.Fatalf("NegotiateLang: got %q %q want zh-Hant zh-hk", dir, lang)
+	}
+}
+
+func TestLangFallback_DefaultEngine(t *testing.T) {
+	root := withTempTemplateRoot(t)
+	resetGlobals(t, root)
+
+	writeTemplateFile(t, root, "default", "hello", "default")
+	writeTemplateFile(t, root, "en", "hello", "en")
+	LangFallback = LangFallbackMap(map[string][]string{"en-AU": {"en-GB"}})
+
+	tmpl, err := LoadHtml("hello", "en-AU")
+	if err != nil {
+		t.Fatalf("LoadHtml: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got, want := buf.String(), "default"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,386 @@
+package kktemplate
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localeFormat holds the conventions the Format* template functions need for
+// one language. Patterns use {0} for the value and ¤ for the currency symbol.
+type localeFormat struct {
+	decimal string
+	group   string
+
+	currency string
+	percent  string
+
+	dateShort  string
+	dateMedium string
+	dateLong   string
+	timeShort  string
+	timeMedium string
+	am, pm     string
+	months     []string
+	monthsAbbr []string
+
+	listPair   string
+	listMiddle string
+	listEnd    string
+
+	now    string
+	future string
+	past   string
+	units  map[string][2]string
+}
+
+var englishMonths = []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+var englishMonthsAbbr = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// localeFormats is keyed by lower-case language tag; lookups try the full
+// tag, then the base language, then en.
+var localeFormats = map[string]*localeFormat{
+	"en": {
+		decimal: ".", group: ",", currency: "¤{0}", percent: "{0}%",
+		dateShort: "1/2/06", dateMedium: "Jan 2, 2006", dateLong: "January 2, 2006",
+		timeShort: "3:04 PM", timeMedium: "3:04:05 PM", am: "AM", pm: "PM",
+		listPair: " and ", listMiddle: ", ", listEnd: ", and ",
+		now: "now", future: "in {0} {1}", past: "{0} {1} ago",
+		units: map[string][2]string{
+			"second": {"second", "seconds"}, "minute": {"minute", "minutes"}, "hour": {"hour", "hours"},
+			"day": {"day", "days"}, "week": {"week", "weeks"}, "month": {"month", "months"}, "year": {"year", "years"},
+		},
+	},
+	"de": {
+		decimal: ",", group: ".", currency: "{0}\u00a0¤", percent: "{0}\u00a0%",
+		dateShort: "02.01.06", dateMedium: "02.01.2006", dateLong: "2. January 2006",
+		timeShort: "15:04", timeMedium: "15:04:05",
+		months:     []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		monthsAbbr: []string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		listPair:   " und ", listMiddle: ", ", listEnd: " und ",
+		now: "jetzt", future: "in {0} {1}", past: "vor {0} {1}",
+		units: map[string][2]string{
+			"second": {"Sekunde", "Sekunden"}, "minute": {"Minute", "Minuten"}, "hour": {"Stunde", "Stunden"},
+			"day": {"Tag", "Tagen"}, "week": {"Woche", "Wochen"}, "month": {"Monat", "Monaten"}, "year": {"Jahr", "Jahren"},
+		},
+	},
+	"fr": {
+		decimal: ",", group: "\u202f", currency: "{0}\u00a0¤", percent: "{0}\u00a0%",
+		dateShort: "02/01/2006", dateMedium: "2 Jan 2006", dateLong: "2 January 2006",
+		timeShort: "15:04", timeMedium: "15:04:05",
+		months:     []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		monthsAbbr: []string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		listPair:   " et ", listMiddle: ", ", listEnd: " et ",
+		now: "maintenant", future: "dans {0} {1}", past: "il y a {0} {1}",
+		units: map[string][2]string{
+			"second": {"seconde", "secondes"}, "minute": {"minute", "minutes"}, "hour": {"heure", "heures"},
+			"day": {"jour", "jours"}, "week": {"semaine", "semaines"}, "month": {"mois", "mois"}, "year": {"an", "ans"},
+		},
+	},
+	"es": {
+		decimal: ",", group: ".", currency: "{0}\u00a0¤", percent: "{0}\u00a0%",
+		dateShort: "2/1/06", dateMedium: "2 Jan 2006", dateLong: "2 de January de 2006",
+		timeShort: "15:04", timeMedium: "15:04:05",
+		months:     []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		monthsAbbr: []string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		listPair:   " y ", listMiddle: ", ", listEnd: " y ",
+		now: "ahora", future: "dentro de {0} {1}", past: "hace {0} {1}",
+		units: map[string][2]string{
+			"second": {"segundo", "segundos"}, "minute": {"minuto", "minutos"}, "hour": {"hora", "horas"},
+			"day": {"día", "días"}, "week": {"semana", "semanas"}, "month": {"mes", "meses"}, "year": {"año", "años"},
+		},
+	},
+	"pt": {
+		decimal: ",", group: ".", currency: "¤\u00a0{0}", percent: "{0}%",
+		dateShort: "02/01/2006", dateMedium: "2 de Jan de 2006", dateLong: "2 de January de 2006",
+		timeShort: "15:04", timeMedium: "15:04:05",
+		months:     []string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		monthsAbbr: []string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		listPair:   " e ", listMiddle: ", ", listEnd: " e ",
+		now: "agora", future: "em {0} {1}", past: "há {0} {1}",
+		units: map[string][2]string{
+			"second": {"segundo", "segundos"}, "minute": {"minuto", "minutos"}, "hour": {"hora", "horas"},
+			"day": {"dia", "dias"}, "week": {"semana", "semanas"}, "month": {"mês", "meses"}, "year": {"ano", "anos"},
+		},
+	},
+	"ja": {
+		decimal: ".", group: ",", currency: "¤{0}", percent: "{0}%",
+		dateShort: "2006/01/02", dateMedium: "2006/01/02", dateLong: "2006年1月2日",
+		timeShort: "15:04", timeMedium: "15:04:05",
+		listPair: "、", listMiddle: "、", listEnd: "、",
+		now: "今", future: "{0} {1}後", past: "{0} {1}前",
+		units: map[string][2]string{
+			"second": {"秒", "秒"}, "minute": {"分", "分"}, "hour": {"時間", "時間"},
+			"day": {"日", "日"}, "week": {"週間", "週間"}, "month": {"か月", "か月"}, "year": {"年", "年"},
+		},
+	},
+	"ko": {
+		decimal: ".", group: ",", currency: "¤{0}", percent: "{0}%",
+		dateShort: "06. 1. 2.", dateMedium: "2006. 1. 2.", dateLong: "2006년 1월 2일",
+		timeShort: "PM 3:04", timeMedium: "PM 3:04:05", am: "오전", pm: "오후",
+		listPair: " 및 ", listMiddle: ", ", listEnd: " 및 ",
+		now: "지금", future: "{0}{1} 후", past: "{0}{1} 전",
+		units: map[string][2]string{
+			"second": {"초", "초"}, "minute": {"분", "분"}, "hour": {"시간", "시간"},
+			"day": {"일", "일"}, "week": {"주", "주"}, "month": {"개월", "개월"}, "year": {"년", "년"},
+		},
+	},
+	"zh": {
+		decimal: ".", group: ",", currency: "¤{0}", percent: "{0}%",
+		dateShort: "2006/1/2", dateMedium: "2006年1月2日", dateLong: "2006年1月2日",
+		timeShort: "15:04", timeMedium: "15:04:05",
+		listPair: "和", listMiddle: "、", listEnd: "和",
+		now: "现在", future: "{0}{1}后", past: "{0}{1}前",
+		units: map[string][2]string{
+			"second": {"秒钟", "秒钟"}, "minute": {"分钟", "分钟"}, "hour": {"小时", "小时"},
+			"day": {"天", "天"}, "week": {"周", "周"}, "month": {"个月", "个月"}, "year": {"年", "年"},
+		},
+	},
+	"zh-tw": {
+		decimal: ".", group: ",", currency: "¤{0}", percent: "{0}%",
+		dateShort: "2006/1/2", dateMedium: "2006年1月2日", dateLong: "2006年1月2日",
+		timeShort: "15:04", timeMedium: "15:04:05",
+		listPair: "和", listMiddle: "、", listEnd: "和",
+		now: "現在", future: "{0} {1}後", past: "{0} {1}前",
+		units: map[string][2]string{
+			"second": {"秒", "秒"}, "minute": {"分鐘", "分鐘"}, "hour": {"小時", "小時"},
+			"day": {"天", "天"}, "week": {"週", "週"}, "month": {"個月", "個月"}, "year": {"年", "年"},
+		},
+	},
+}
+
+func init() {
+	localeFormats["zh-hk"] = localeFormats["zh-tw"]
+	localeFormats["zh-hant"] = localeFormats["zh-tw"]
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$", "EUR": "€", "GBP": "£", "JPY": "¥", "CNY": "CN¥", "TWD": "NT$", "HKD": "HK$", "KRW": "₩", "BRL": "R$",
+}
+
+// currencyDigits lists currencies without the default two minor digits.
+var currencyDigits = map[string]int{"JPY": 0, "KRW": 0}
+
+func localeFor(lang string) *localeFormat {
+	tag := strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	if format, ok := localeFormats[tag]; ok {
+		return format
+	}
+	if format, ok := localeFormats[strings.SplitN(tag, "-", 2)[0]]; ok {
+		return format
+	}
+	return localeFormats["en"]
+}
+
+// localeFuncs returns the Format* template functions bound to lang. The
+// optional tz argument of FormatDate, FormatTime and FormatDateTime is an IANA
+// zone name read from the system zone database; programs deployed where it
+// may be missing should import time/tzdata in their main package or build
+// with -tags timetzdata.
+func localeFuncs(lang string) map[string]any {
+	l := localeFor(lang)
+	return map[string]any{
+		"FormatNumber": func(v any, decimals ...int) (string, error) {
+			n, ok := toFloat(v)
+			if !ok {
+				return "", fmt.Errorf("FormatNumber: %v is not a number", v)
+			}
+			return l.number(n, optionalDigits(decimals)), nil
+		},
+		"FormatCurrency": func(v any, code string) (string, error) {
+			n, ok := toFloat(v)
+			if !ok {
+				return "", fmt.Errorf("FormatCurrency: %v is not a number", v)
+			}
+			return l.currencyAmount(n, code), nil
+		},
+		"FormatPercent": func(v any, decimals ...int) (string, error) {
+			n, ok := toFloat(v)
+			if !ok {
+				return "", fmt.Errorf("FormatPercent: %v is not a number", v)
+			}
+			digits := optionalDigits(decimals)
+			if digits < 0 {
+				digits = 0
+			}
+			return strings.Replace(l.percent, "{0}", l.number(n*100, digits), 1), nil
+		},
+		"FormatDate": func(t time.Time, style string, tz ...string) (string, error) {
+			return l.dateTime(t, style, "", tz)
+		},
+		"FormatTime": func(t time.Time, style string, tz ...string) (string, error) {
+			return l.dateTime(t, "", style, tz)
+		},
+		"FormatDateTime": func(t time.Time, style string, tz ...string) (string, error) {
+			return l.dateTime(t, style, style, tz)
+		},
+		"FormatRelativeTime": func(v any) (string, error) {
+			switch value := v.(type) {
+			case time.Time:
+				return l.relative(time.Until(value), lang), nil
+			case time.Duration:
+				return l.relative(value, lang), nil
+			}
+			return "", fmt.Errorf("FormatRelativeTime: %T is neither time.Time nor time.Duration", v)
+		},
+		"FormatList": func(items any) (string, error) {
+			rv := reflect.ValueOf(items)
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				return "", fmt.Errorf("FormatList: %T is not a list", items)
+			}
+			parts := make([]string, rv.Len())
+			for i := range parts {
+				parts[i] = fmt.Sprint(rv.Index(i).Interface())
+			}
+			return l.list(parts), nil
+		},
+	}
+}
+
+func optionalDigits(decimals []int) int {
+	if len(decimals) > 0 {
+		return decimals[0]
+	}
+	return -1
+}
+
+// number formats n with digits fraction digits (as many as needed when
+// digits < 0) and locale grouping of the integer part.
+func (l *localeFormat) number(n float64, digits int) string {
+	s := strconv.FormatFloat(math.Abs(n), 'f', digits, 64)
+	integer, fraction, _ := strings.Cut(s, ".")
+
+	var grouped strings.Builder
+	for i, r := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(l.group)
+		}
+		grouped.WriteRune(r)
+	}
+	if fraction != "" {
+		grouped.WriteString(l.decimal)
+		grouped.WriteString(fraction)
+	}
+
+	if n < 0 && strings.Trim(s, "0.") != "" {
+		return "-" + grouped.String()
+	}
+	return grouped.String()
+}
+
+func (l *localeFormat) currencyAmount(n float64, code string) string {
+	code = strings.ToUpper(code)
+	digits, ok := currencyDigits[code]
+	if !ok {
+		digits = 2
+	}
+	symbol, ok := currencySymbols[code]
+	if !ok {
+		symbol = code + "\u00a0"
+		if strings.HasPrefix(l.currency, "{0}") {
+			symbol = code
+		}
+	}
+
+	amount := l.number(math.Abs(n), digits)
+	out := strings.Replace(strings.Replace(l.currency, "{0}", amount, 1), "¤", symbol, 1)
+	if n < 0 && amount != l.number(0, digits) {
+		return "-" + out
+	}
+	return out
+}
+
+func (l *localeFormat) dateTime(t time.Time, dateStyle string, timeStyle string, tz []string) (string, error) {
+	if len(tz) > 0 && tz[0] != "" {
+		location, err := time.LoadLocation(tz[0])
+		if err != nil {
+			return "", err
+		}
+		t = t.In(location)
+	}
+
+	var parts []string
+	if dateStyle != "" {
+		layout := map[string]string{"short": l.dateShort, "medium": l.dateMedium, "long": l.dateLong}[dateStyle]
+		if layout == "" {
+			return "", fmt.Errorf("unknown date style %q", dateStyle)
+		}
+		parts = append(parts, l.localizeMonth(t, t.Format(layout)))
+	}
+	if timeStyle != "" {
+		layout := map[string]string{"short": l.timeShort, "medium": l.timeMedium, "long": l.timeMedium + " MST"}[timeStyle]
+		if layout == "" {
+			return "", fmt.Errorf("unknown time style %q", timeStyle)
+		}
+		out := t.Format(layout)
+		if l.am != "" && l.am != "AM" {
+			out = strings.Replace(strings.Replace(out, "AM", l.am, 1), "PM", l.pm, 1)
+		}
+		parts = append(parts, out)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// localizeMonth swaps the English month name Go's layout produced for the
+// locale's.
+func (l *localeFormat) localizeMonth(t time.Time, s string) string {
+	month := int(t.Month()) - 1
+	if l.months != nil && strings.Contains(s, englishMonths[month]) {
+		return strings.Replace(s, englishMonths[month], l.months[month], 1)
+	}
+	if l.monthsAbbr != nil && strings.Contains(s, englishMonthsAbbr[month]) {
+		return strings.Replace(s, englishMonthsAbbr[month], l.monthsAbbr[month], 1)
+	}
+	return s
+}
+
+// relative describes d (positive: future) in the largest fitting unit.
+func (l *localeFormat) relative(d time.Duration, lang string) string {
+	seconds := math.Abs(d.Seconds())
+	if seconds < 1 {
+		return l.now
+	}
+
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"year", 365 * 24 * 3600}, {"month", 30 * 24 * 3600}, {"week", 7 * 24 * 3600},
+		{"day", 24 * 3600}, {"hour", 3600}, {"minute", 60}, {"second", 1},
+	}
+	unit := units[len(units)-1]
+	for _, candidate := range units {
+		if seconds >= candidate.seconds {
+			unit = candidate
+			break
+		}
+	}
+
+	count := int64(math.Round(seconds / unit.seconds))
+	forms := l.units[unit.name]
+	name := forms[1]
+	if PluralCategory(lang, count) == "one" {
+		name = forms[0]
+	}
+
+	pattern := l.future
+	if d < 0 {
+		pattern = l.past
+	}
+	return strings.Replace(strings.Replace(pattern, "{0}", strconv.FormatInt(count, 10), 1), "{1}", name, 1)
+}
+
+func (l *localeFormat) list(parts []string) string {
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	case 2:
+		return parts[0] + l.listPair + parts[1]
+	}
+	return strings.Join(parts[:len(parts)-1], l.listMiddle) + l.listEnd + parts[len(parts)-1]
+}
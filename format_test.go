@@ -0,0 +1,184 @@
+// format_test.go contains unit tests for the locale-aware Format* template functions.
+//
+// Test Case Index:
+// - TestFormatNumber: grouping and decimal separators follow the language.
+// - TestFormatCurrencyAndPercent: currency symbols, minor digits and percent patterns follow the language.
+// - TestFormatDateTime: date and time styles are localized and converted to the requested IANA time zone, skipping zones the host lacks.
+// - TestFormatRelativeTime: durations are described in the largest unit with plural-aware unit names.
+// - TestFormatList: lists are joined with the language's conjunction.
+// - TestFormatFuncs_Template: the functions are bound to the lang the template was loaded for.
+package kktemplate
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func callFormat(t *testing.T, lang string, name string, args ...any) string {
+	t.Helper()
+	fn := localeFuncs(lang)[name]
+	var out string
+	var err error
+	switch f := fn.(type) {
+	case func(any, ...int) (string, error):
+		var digits []int
+		for _, arg := range args[1:] {
+			digits = append(digits, arg.(int))
+		}
+		out, err = f(args[0], digits...)
+	case func(any, string) (string, error):
+		out, err = f(args[0], args[1].(string))
+	case func(time.Time, string, ...string) (string, error):
+		var tz []string
+		for _, arg := range args[2:] {
+			tz = append(tz, arg.(string))
+		}
+		out, err = f(args[0].(time.Time), args[1].(string), tz...)
+	case func(any) (string, error):
+		out, err = f(args[0])
+	default:
+		t.Fatalf("unexpected function type %T", fn)
+	}
+	if err != nil {
+		t.Fatalf("%s(%v): %v", name, args, err)
+	}
+	return out
+}
+
+func TestFormatNumber(t *testing.T) {
+	cases := []struct {
+		lang string
+		args []any
+		want string
+	}{
+		{"en-US", []any{1234567.891, 2}, "1,234,567.89"},
+		{"de", []any{1234567.891, 1}, "1.234.567,9"},
+		{"fr-FR", []any{-1234.5}, "-1\u202f234,5"},
+		{"ja", []any{42}, "42"},
+		{"xx", []any{1000}, "1,000"},
+	}
+	for _, c := range cases {
+		if got := callFormat(t, c.lang, "FormatNumber", c.args...); got != c.want {
+			t.Fatalf("FormatNumber(%s, %v): got %q want %q", c.lang, c.args, got, c.want)
+		}
+	}
+}
+
+func TestFormatCurrencyAndPercent(t *testing.T) {
+	cases := []struct {
+		lang, name string
+		args       []any
+		want       string
+	}{
+		{"en", "FormatCurrency", []any{1234.5, "USD"}, "$1,234.50"},
+		{"en", "FormatCurrency", []any{-3, "usd"}, "-$3.00"},
+		{"de", "FormatCurrency", []any{1234.5, "EUR"}, "1.234,50\u00a0€"},
+		{"ja", "FormatCurrency", []any{1500, "JPY"}, "¥1,500"},
+		{"pt-BR", "FormatCurrency", []any{10, "BRL"}, "R$\u00a010,00"},
+		{"en", "FormatCurrency", []any{10, "CHF"}, "CHF\u00a010.00"},
+		{"en", "FormatPercent", []any{0.256}, "26%"},
+		{"fr", "FormatPercent", []any{0.256, 1}, "25,6\u00a0%"},
+	}
+	for _, c := range cases {
+		if got := callFormat(t, c.lang, c.name, c.args...); got != c.want {
+			t.Fatalf("%s(%s, %v): got %q want %q", c.name, c.lang, c.args, got, c.want)
+		}
+	}
+}
+
+func TestFormatDateTime(t *testing.T) {
+	ts := time.Date(2024, time.March, 5, 14, 7, 9, 0, time.UTC)
+	cases := []struct {
+		lang, name, style string
+		tz                []string
+		want              string
+	}{
+		{"en", "FormatDate", "medium", nil, "Mar 5, 2024"},
+		{"en", "FormatDate", "short", nil, "3/5/24"},
+		{"de", "FormatDate", "long", nil, "5. März 2024"},
+		{"fr", "FormatDate", "medium", nil, "5 mars 2024"},
+		{"zh-TW", "FormatDate", "long", nil, "2024年3月5日"},
+		{"en", "FormatTime", "short", nil, "2:07 PM"},
+		{"ko", "FormatTime", "short", nil, "오후 2:07"},
+		{"ja", "FormatDateTime", "short", []string{"Asia/Tokyo"}, "2024/03/05 23:07"},
+		{"en", "FormatTime", "short", []string{"America/New_York"}, "9:07 AM"},
+		{"de", "FormatDateTime", "short", []string{"Europe/Berlin"}, "05.03.24 15:07"},
+		{"en", "FormatTime", "short", []string{"UTC"}, "2:07 PM"},
+	}
+	for _, c := range cases {
+		args := []any{ts, c.style}
+		for _, tz := range c.tz {
+			args = append(args, tz)
+		}
+		if len(c.tz) > 0 {
+			// The zone database is not guaranteed on every host.
+			if _, err := time.LoadLocation(c.tz[0]); err != nil {
+				t.Logf("skipping %s(%s, %s): %v", c.name, c.lang, c.tz[0], err)
+				continue
+			}
+		}
+		if got := callFormat(t, c.lang, c.name, args...); got != c.want {
+			t.Fatalf("%s(%s, %s): got %q want %q", c.name, c.lang, c.style, got, c.want)
+		}
+	}
+
+	if _, err := localeFuncs("en")["FormatDate"].(func(time.Time, string, ...string) (string, error))(ts, "bogus"); err == nil {
+		t.Fatalf("expected an error for an unknown style")
+	}
+}
+
+func TestFormatRelativeTime(t *testing.T) {
+	cases := []struct {
+		lang string
+		d    time.Duration
+		want string
+	}{
+		{"en", 0, "now"},
+		{"en", -time.Minute, "1 minute ago"},
+		{"en", 3 * 24 * time.Hour, "in 3 days"},
+		{"de", -2 * time.Hour, "vor 2 Stunden"},
+		{"fr", 400 * 24 * time.Hour, "dans 1 an"},
+		{"zh-TW", -14 * 24 * time.Hour, "2 週前"},
+		{"ja", 45 * time.Second, "45 秒後"},
+	}
+	for _, c := range cases {
+		if got := callFormat(t, c.lang, "FormatRelativeTime", c.d); got != c.want {
+			t.Fatalf("FormatRelativeTime(%s, %v): got %q want %q", c.lang, c.d, got, c.want)
+		}
+	}
+}
+
+func TestFormatList(t *testing.T) {
+	cases := []struct {
+		lang  string
+		items []string
+		want  string
+	}{
+		{"en", []string{"a"}, "a"},
+		{"en", []string{"a", "b"}, "a and b"},
+		{"en", []string{"a", "b", "c"}, "a, b, and c"},
+		{"de", []string{"a", "b", "c"}, "a, b und c"},
+		{"zh", []string{"甲", "乙", "丙"}, "甲、乙和丙"},
+	}
+	for _, c := range cases {
+		if got := callFormat(t, c.lang, "FormatList", c.items); got != c.want {
+			t.Fatalf("FormatList(%s, %v): got %q want %q", c.lang, c.items, got, c.want)
+		}
+	}
+}
+
+func TestFormatFuncs_Template(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{FormatNumber .N 2}} {{FormatList .Items}}"})
+	data := map[string]any{"N": 1234.5, "Items": []string{"x", "<y>"}}
+
+	for lang, want := range map[string]string{"en": "1,234.50 x and &lt;y&gt;", "de": "1.234,50 x und &lt;y&gt;"} {
+		var buf bytes.Buffer
+		if err := e.RenderHtml(&buf, "page", lang, data); err != nil {
+			t.Fatalf("RenderHtml(%s): %v", lang, err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("RenderHtml(%s): got %q want %q", lang, got, want)
+		}
+	}
+}
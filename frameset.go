@@ -0,0 +1,196 @@
+package kktemplate
+
+import (
+	"fmt"
+	html "html/template"
+
+	"github.com/yetiz-org/goth-kklogger"
+)
+
+func RegisterFrameSet(set string, frames []string) {
+	defaultEngine.RegisterFrameSet(set, frames)
+}
+
+// RegisterFrameSet registers frames as the frame set set, to be composed with
+// pages through LoadFrameSetHtml instead of the struct template frames. Each
+// set has its own existence check and cache. A nil or empty frames removes
+// the set; re-registering a set drops the pages cached with it.
+func (e *Engine) RegisterFrameSet(set string, frames []string) {
+	if e == nil || e.frameLocker == nil {
+		return
+	}
+	e.frameLocker.Lock()
+	if len(frames) == 0 {
+		delete(e.frameSets, set)
+	} else {
+		if e.frameSets == nil {
+			e.frameSets = map[string][]string{}
+		}
+		e.frameSets[set] = append([]string(nil), frames...)
+	}
+	delete(e.frameSetExist, set)
+	e.frameLocker.Unlock()
+	e.invalidateFrameSet(set)
+}
+
+func FrameSets() []string {
+	return defaultEngine.FrameSets()
+}
+
+// FrameSets lists the names of the registered frame sets, sorted.
+func (e *Engine) FrameSets() []string {
+	if e == nil || e.frameLocker == nil {
+		return nil
+	}
+	e.frameLocker.Lock()
+	defer e.frameLocker.Unlock()
+	return sortedKeys(e.frameSets)
+}
+
+func (e *Engine) frameSet(set string) ([]string, bool) {
+	e.frameLocker.Lock()
+	defer e.frameLocker.Unlock()
+	frames, ok := e.frameSets[set]
+	return frames, ok
+}
+
+func LoadFrameSetHtml(set string, name string, lang string) (*html.Template, error) {
+	return defaultEngine.LoadFrameSetHtml(set, name, lang)
+}
+
+// LoadFrameSetHtml is LoadFrameHtml with the frames of the frame set set.
+func (e *Engine) LoadFrameSetHtml(set string, name string, lang string) (*html.Template, error) {
+	tmpl, _, err := e.loadFrameSetHtml(set, name, lang)
+	return tmpl, err
+}
+
+func (e *Engine) loadFrameSetHtml(set string, name string, lang string) (*html.Template, *htmlCopies, error) {
+	if e == nil || e.htmlLocker == nil || e.frameLocker == nil {
+		return nil, nil, ErrInvalidEngine
+	}
+	frames, ok := e.frameSet(set)
+	if !ok {
+		return nil, nil, fmt.Errorf("frame set %q is not registered: %w", set, ErrTemplateNotFound)
+	}
+
+	entry := cacheEntry{kind: cacheFrameSet, set: set, name: name, lang: lang}
+	mapName := entry.mapName()
+	if e.isDebug() {
+		e.htmlLocker.Lock()
+		dropTemplate(e.htmlTemplateCopies, e.frameSetTemplateMap, mapName)
+		e.htmlLocker.Unlock()
+	}
+
+	e.htmlLocker.Lock()
+	tmpl, copies := cachedTemplate(e.htmlTemplateCopies, e.frameSetTemplateMap, mapName)
+	e.htmlLocker.Unlock()
+	if copies != nil {
+		return tmpl, copies, nil
+	}
+
+	if err := e.frameSetExistValidate(set, frames, lang); err != nil {
+		return nil, nil, err
+	}
+
+	parsed, chain, err := e.parseFrameHtml(name, lang, frames)
+	if err != nil {
+		return nil, nil, err
+	}
+	if copies, err = e.newHTMLCopies(parsed); err != nil {
+		return nil, nil, err
+	}
+	entry.deps = append(layoutChainNames(chain), frames...)
+
+	e.htmlLocker.Lock()
+	if e.frameSetTemplateMap == nil {
+		e.frameSetTemplateMap = map[string]*html.Template{}
+	}
+	tmpl, copies, stored := storeTemplate(&e.htmlTemplateCopies, e.frameSetTemplateMap, mapName, parsed, copies)
+	e.htmlLocker.Unlock()
+	if stored {
+		e.trackCache(entry)
+	}
+	return tmpl, copies, nil
+}
+
+// frameSetExistValidate is frameExistValidate for the frame set set.
+func (e *Engine) frameSetExistValidate(set string, frames []string, lang string) error {
+	e.frameLocker.Lock()
+	defer e.frameLocker.Unlock()
+	if e.frameSetExist[set][lang] && !e.isDebug() {
+		return nil
+	}
+	if err := e.checkFrames(set, frames, lang); err != nil {
+		kklogger.ErrorJ("kktemplate:_FrameSetExistValidate", err.Error())
+		return err
+	}
+	if e.frameSetExist == nil {
+		e.frameSetExist = map[string]map[string]bool{}
+	}
+	if e.frameSetExist[set] == nil {
+		e.frameSetExist[set] = map[string]bool{}
+	}
+	e.frameSetExist[set][lang] = true
+	return nil
+}
+
+// invalidateFrameSet drops every page cached with the frame set set.
+func (e *Engine) invalidateFrameSet(set string) {
+	e.entryLocker.Lock()
+	var dropped []cacheEntry
+	for key, entry := range e.cacheEntries {
+		if entry.kind == cacheFrameSet && entry.set == set {
+			dropped = append(dropped, entry)
+			delete(e.cacheEntries, key)
+		}
+	}
+	e.entryLocker.Unlock()
+
+	for _, entry := range dropped {
+		e.dropCache(entry)
+	}
+}
+
+// resetFrameChecks resets the existence check of the struct template frames
+// and of every frame set that contains one of names.
+func (e *Engine) resetFrameChecks(names map[string]bool) {
+	if e.frameExist == nil || e.frameLocker == nil {
+		return
+	}
+	for _, frame := range e.structTemplateFramesValue() {
+		if names[frame] {
+			e.frameLocker.Lock()
+			clear(*e.frameExist)
+			e.frameLocker.Unlock()
+			break
+		}
+	}
+
+	e.frameLocker.Lock()
+	defer e.frameLocker.Unlock()
+	for set, frames := range e.frameSets {
+		for _, frame := range frames {
+			if names[frame] {
+				delete(e.frameSetExist, set)
+				break
+			}
+		}
+	}
+}
+
+// allFrames lists the struct template frames and the frames of every frame
+// set, sorted and without duplicates.
+func (e *Engine) allFrames() []string {
+	seen := map[string]bool{}
+	for _, frame := range e.structTemplateFramesValue() {
+		seen[frame] = true
+	}
+	e.frameLocker.Lock()
+	for _, frames := range e.frameSets {
+		for _, frame := range frames {
+			seen[frame] = true
+		}
+	}
+	e.frameLocker.Unlock()
+	return sortedKeys(seen)
+}
@@ -0,0 +1,120 @@
+// frameset_test.go contains unit tests for named frame sets.
+//
+// Test Case Index:
+// - TestFrameSet_Render: one page renders with the frames of different sets.
+// - TestFrameSet_ExistValidation: each set validates its own frames, independently of the struct template frames.
+// - TestFrameSet_NotRegistered: loading with an unknown set fails with ErrTemplateNotFound.
+// - TestFrameSet_CacheNamespace: sets are cached apart and invalidated by their own frames and by re-registering.
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func newFrameSetEngine() (*Engine, fstest.MapFS) {
+	e, fsys := newMapFSEngine(map[string]string{
+		"default/page.tmpl":         "{{template \"_shell.tmpl\" .}}",
+		"default/_admin_shell.tmpl": "{{define \"_shell.tmpl\"}}admin:{{.}}{{end}}",
+		"default/_mail_shell.tmpl":  "{{define \"_shell.tmpl\"}}mail:{{.}}{{end}}",
+	})
+	e.RegisterFrameSet("admin", []string{"_admin_shell"})
+	e.RegisterFrameSet("mail", []string{"_mail_shell"})
+	return e, fsys
+}
+
+func TestFrameSet_Render(t *testing.T) {
+	e, _ := newFrameSetEngine()
+
+	for set, want := range map[string]string{"admin": "admin:x", "mail": "mail:x"} {
+		var buf bytes.Buffer
+		if err := e.RenderFrameSet(&buf, set, "page", "en", "x"); err != nil {
+			t.Fatalf("RenderFrameSet(%s): %v", set, err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("RenderFrameSet(%s): got %q want %q", set, got, want)
+		}
+	}
+	if got, want := e.FrameSets(), []string{"admin", "mail"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FrameSets: got %v want %v", got, want)
+	}
+}
+
+func TestFrameSet_ExistValidation(t *testing.T) {
+	e, fsys := newFrameSetEngine()
+	e.RegisterFrameSet("site", []string{"_site_shell"})
+
+	_, err := e.LoadFrameSetHtml("site", "page", "en")
+	var missing *MissingFrameError
+	if !errors.Is(err, ErrTemplateNotFound) || !errors.As(err, &missing) || missing.Set != "site" || missing.Frame != "_site_shell" {
+		t.Fatalf("site: expected _site_shell of site to be missing, got %v", err)
+	}
+	if _, err := e.LoadFrameHtml("page", "en"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("struct frames: expected ErrTemplateNotFound, got %v", err)
+	}
+	if _, err := e.LoadFrameSetHtml("admin", "page", "en"); err != nil {
+		t.Fatalf("admin: %v", err)
+	}
+
+	fsys["default/_site_shell.tmpl"] = &fstest.MapFile{Data: []byte("{{define \"_shell.tmpl\"}}site{{end}}")}
+	if _, err := e.LoadFrameSetHtml("site", "page", "en"); err != nil {
+		t.Fatalf("site after adding the frame: %v", err)
+	}
+
+	delete(fsys, "default/_site_shell.tmpl")
+	e.Invalidate("_site_shell", "")
+	if _, err := e.LoadFrameSetHtml("site", "page", "en"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("site after removing the frame: expected ErrTemplateNotFound, got %v", err)
+	}
+	if _, err := e.LoadFrameSetHtml("admin", "page", "en"); err != nil {
+		t.Fatalf("admin after removing the site frame: %v", err)
+	}
+}
+
+func TestFrameSet_NotRegistered(t *testing.T) {
+	e, _ := newFrameSetEngine()
+
+	var buf bytes.Buffer
+	err := e.RenderFrameSet(&buf, "missing", "page", "en", nil)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestFrameSet_CacheNamespace(t *testing.T) {
+	e, _ := newFrameSetEngine()
+	for _, set := range []string{"admin", "mail"} {
+		if _, err := e.LoadFrameSetHtml(set, "page", "en"); err != nil {
+			t.Fatalf("LoadFrameSetHtml(%s): %v", set, err)
+		}
+	}
+	if _, err := e.LoadHtml("page", "en"); err != nil {
+		t.Fatalf("LoadHtml: %v", err)
+	}
+
+	want := []string{"frameset:admin:page-en", "frameset:mail:page-en", "html:page-en"}
+	if got := e.CachedKeys(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("CachedKeys: got %v want %v", got, want)
+	}
+
+	e.Invalidate("_admin_shell", "")
+	want = []string{"frameset:mail:page-en", "html:page-en"}
+	if got := e.CachedKeys(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after Invalidate: got %v want %v", got, want)
+	}
+
+	e.RegisterFrameSet("mail", []string{"_admin_shell"})
+	if got, want := e.CachedKeys(), []string{"html:page-en"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after RegisterFrameSet: got %v want %v", got, want)
+	}
+	var buf bytes.Buffer
+	if err := e.RenderFrameSet(&buf, "mail", "page", "en", "x"); err != nil {
+		t.Fatalf("RenderFrameSet: %v", err)
+	}
+	if got, want := buf.String(), "admin:x"; got != want {
+		t.Fatalf("RenderFrameSet: got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package kktemplate
+
+// FuncFactory builds a template function for the language a template is
+// loaded for, the same way T is bound to it.
+type FuncFactory func(lang string) any
+
+func RegisterFuncFactory(name string, factory FuncFactory) {
+	defaultEngine.RegisterFuncFactory(name, factory)
+}
+
+// RegisterFuncFactory makes name available to every template of the engine
+// as factory(lang). Factories take precedence over FuncMap entries of the
+// same name; a nil factory removes name. Cached templates are dropped so they
+// are parsed again with the new function set.
+func (e *Engine) RegisterFuncFactory(name string, factory FuncFactory) {
+	if e == nil {
+		return
+	}
+	e.factoryLocker.Lock()
+	if factory == nil {
+		delete(e.funcFactories, name)
+	} else {
+		if e.funcFactories == nil {
+			e.funcFactories = map[string]FuncFactory{}
+		}
+		e.funcFactories[name] = factory
+	}
+	e.factoryLocker.Unlock()
+	e.InvalidateAll()
+}
+
+// boundFuncs invokes every registered factory for lang.
+func (e *Engine) boundFuncs(lang string) map[string]any {
+	e.factoryLocker.RLock()
+	defer e.factoryLocker.RUnlock()
+	funcs := make(map[string]any, len(e.funcFactories))
+	for name, factory := range e.funcFactories {
+		funcs[name] = factory(lang)
+	}
+	return funcs
+}
@@ -0,0 +1,61 @@
+// funcfactory_test.go contains unit tests for language-bound FuncMap factories.
+//
+// Test Case Index:
+// - TestRegisterFuncFactory_BindsLang: factories receive the lang each html and text template is loaded for.
+// - TestRegisterFuncFactory_OverridesFuncMap: factories win over FuncMap entries and can be removed again.
+package kktemplate
+
+import (
+	"bytes"
+	html "html/template"
+	"testing"
+)
+
+func TestRegisterFuncFactory_BindsLang(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{localURL \"/about\"}}"})
+	e.RegisterFuncFactory("localURL", func(lang string) any {
+		return func(p string) string { return "/" + lang + p }
+	})
+
+	for _, lang := range []string{"en", "zh-TW"} {
+		var buf bytes.Buffer
+		if err := e.RenderHtml(&buf, "page", lang, nil); err != nil {
+			t.Fatalf("RenderHtml(%s): %v", lang, err)
+		}
+		if got, want := buf.String(), "/"+lang+"/about"; got != want {
+			t.Fatalf("RenderHtml(%s): got %q want %q", lang, got, want)
+		}
+		buf.Reset()
+		if err := e.RenderText(&buf, "page", lang, nil); err != nil {
+			t.Fatalf("RenderText(%s): %v", lang, err)
+		}
+		if got, want := buf.String(), "/"+lang+"/about"; got != want {
+			t.Fatalf("RenderText(%s): got %q want %q", lang, got, want)
+		}
+	}
+}
+
+func TestRegisterFuncFactory_OverridesFuncMap(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{asset}}"})
+	e.SetFuncMap(html.FuncMap{"asset": func() string { return "static" }})
+	e.RegisterFuncFactory("asset", func(lang string) any {
+		return func() string { return "asset-" + lang }
+	})
+
+	var buf bytes.Buffer
+	if err := e.RenderText(&buf, "page", "ja", nil); err != nil {
+		t.Fatalf("RenderText: %v", err)
+	}
+	if got, want := buf.String(), "asset-ja"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+
+	e.RegisterFuncFactory("asset", nil)
+	buf.Reset()
+	if err := e.RenderText(&buf, "page", "ja", nil); err != nil {
+		t.Fatalf("RenderText: %v", err)
+	}
+	if got, want := buf.String(), "static"; got != want {
+		t.Fatalf("output mismatch after removal: got %q want %q", got, want)
+	}
+}
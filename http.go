@@ -0,0 +1,167 @@
+package kktemplate
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrorPageFunc writes the response of a request whose page failed to
+// render. status is http.StatusNotFound when the page does not exist and
+// http.StatusInternalServerError otherwise; PageServer also reports
+// http.StatusMethodNotAllowed.
+type ErrorPageFunc func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+// DataFunc builds the data of a page for a request.
+type DataFunc func(r *http.Request) (any, error)
+
+// DefaultErrorPage answers with the status text, like http.Error.
+func DefaultErrorPage(w http.ResponseWriter, r *http.Request, status int, err error) {
+	http.Error(w, http.StatusText(status), status)
+}
+
+func SetErrorPage(fn ErrorPageFunc) {
+	defaultEngine.SetErrorPage(fn)
+}
+
+// SetErrorPage replaces DefaultErrorPage for Render and Handler. A nil fn
+// restores it.
+func (e *Engine) SetErrorPage(fn ErrorPageFunc) {
+	if e == nil {
+		return
+	}
+	e.httpLocker.Lock()
+	e.errorPage = fn
+	e.httpLocker.Unlock()
+}
+
+func (e *Engine) errorPageValue() ErrorPageFunc {
+	e.httpLocker.RLock()
+	defer e.httpLocker.RUnlock()
+	if e.errorPage == nil {
+		return DefaultErrorPage
+	}
+	return e.errorPage
+}
+
+func Render(w http.ResponseWriter, r *http.Request, name string, data any, opts ...RenderOption) error {
+	return defaultEngine.Render(w, r, name, data, opts...)
+}
+
+// Render answers r with the page name negotiated from its Accept-Language
+// header: the page is resolved in the language directory NegotiateLang finds
+// and its T and Format* functions are bound to the language it matches, so
+// only known languages become cache keys. The page is composed with the
+// struct template frames when there are any. It sets Content-Type,
+// Vary: Accept-Language and, unless the page comes from the default
+// directory, Content-Language; a missing page is answered with 404 and any
+// other failure with 500, through the error page. The error is returned for
+// logging.
+func (e *Engine) Render(w http.ResponseWriter, r *http.Request, name string, data any, opts ...RenderOption) error {
+	dir, lang := e.NegotiateLang(name, r.Header.Values("Accept-Language")...)
+	addVary(w.Header(), "Accept-Language")
+	return e.renderPage(w, r, "", name, dir, lang, data, opts)
+}
+
+// renderPage renders name for lang with the frame set set, or with the struct
+// template frames when set is "" and there are any, and writes the response
+// with dir as its Content-Language.
+// The error page is used while nothing has been written, which is always the
+// case unless opts include Streaming.
+func (e *Engine) renderPage(w http.ResponseWriter, r *http.Request, set string, name string, dir string, lang string, data any, opts []RenderOption) error {
+	page := &pageWriter{ResponseWriter: w, lang: dir}
+	var err error
+	switch {
+	case set != "":
+		err = e.RenderFrameSet(page, set, name, lang, data, opts...)
+	case len(e.structTemplateFramesValue()) > 0:
+		err = e.RenderFrame(page, name, lang, data, opts...)
+	default:
+		err = e.RenderHtml(page, name, lang, data, opts...)
+	}
+	if err != nil && !page.written {
+		e.errorPageValue()(w, r, renderStatus(err), err)
+	}
+	return err
+}
+
+// pageWriter sets the page headers right before the first write or flush.
+type pageWriter struct {
+	http.ResponseWriter
+	lang    string
+	written bool
+}
+
+func (w *pageWriter) Write(p []byte) (int, error) {
+	w.writeHeaders()
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends what a streaming render has written so far.
+func (w *pageWriter) Flush() {
+	w.writeHeaders()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *pageWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *pageWriter) writeHeaders() {
+	if w.written {
+		return
+	}
+	w.written = true
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	if w.lang != "default" {
+		w.Header().Set("Content-Language", w.lang)
+	}
+}
+
+func Handler(name string, data DataFunc) http.Handler {
+	return defaultEngine.Handler(name, data)
+}
+
+// Handler serves the page name with Render. data, when not nil, builds the
+// page data for each request; its errors are answered with 500.
+func (e *Engine) Handler(name string, data DataFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pageData any
+		if data != nil {
+			var err error
+			if pageData, err = data(r); err != nil {
+				addVary(w.Header(), "Accept-Language")
+				e.errorPageValue()(w, r, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		_ = e.Render(w, r, name, pageData)
+	})
+}
+
+// renderStatus maps a render error to its HTTP status. A missing frame or
+// layout is a server fault rather than a missing page.
+func renderStatus(err error) int {
+	var missingFrame *MissingFrameError
+	var missingLayout *MissingLayoutError
+	if errors.Is(err, ErrTemplateNotFound) && !errors.As(err, &missingFrame) && !errors.As(err, &missingLayout) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func addVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
@@ -0,0 +1,213 @@
+// http_test.go contains unit tests for the net/http integration.
+//
+// Test Case Index:
+// - TestHTTPRender_Negotiates: Render picks the page language from Accept-Language and sets the response headers.
+// - TestHTTPRender_DefaultOnly: a page found only in default is formatted for each known language, without Content-Language, and unknown tags share the default entry.
+// - TestHTTPRender_NotFound: a missing page is answered with 404 by the default error page.
+// - TestHTTPRender_ErrorPage: execute failures, missing layouts and missing frames go to the configured error page with 500.
+// - TestHTTPRender_Streaming: a late execute failure is a clean 500, or keeps the partial 200 with Streaming.
+// - TestHTTPPageWriter_Flush: the page writer flushes through http.ResponseController and sets the headers first.
+// - TestHTTPHandler_Data: Handler renders with the data of its DataFunc and answers DataFunc errors with 500.
+package kktemplate
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newHTTPEngine(files map[string]string) *Engine {
+	for _, frame := range StructTemplateFrames {
+		if _, ok := files["default/"+frame+".tmpl"]; !ok {
+			files["default/"+frame+".tmpl"] = ""
+		}
+	}
+	e, _ := newMapFSEngine(files)
+	return e
+}
+
+func TestHTTPRender_Negotiates(t *testing.T) {
+	e := newHTTPEngine(map[string]string{
+		"default/home.tmpl": "home {{.}}",
+		"ja/home.tmpl":      "ホーム {{.}}",
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "ja-JP,ja;q=0.9,en;q=0.8")
+	w := httptest.NewRecorder()
+	if err := e.Render(w, r, "home", "<x>"); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d want 200", w.Code)
+	}
+	if got, want := w.Body.String(), "ホーム &lt;x&gt;"; got != want {
+		t.Fatalf("body: got %q want %q", got, want)
+	}
+	headers := map[string]string{
+		"Content-Type":     "text/html; charset=utf-8",
+		"Content-Language": "ja",
+		"Vary":             "Accept-Language",
+	}
+	for key, want := range headers {
+		if got := w.Header().Get(key); got != want {
+			t.Fatalf("%s: got %q want %q", key, got, want)
+		}
+	}
+}
+
+func TestHTTPRender_DefaultOnly(t *testing.T) {
+	e := newHTTPEngine(map[string]string{"default/home.tmpl": "{{FormatNumber 1234.5 1}}"})
+
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"de-DE", "1.234,5"},
+		{"de-AT,en;q=0.5", "1.234,5"},
+		{"en-US", "1,234.5"},
+		{"x-klingon", "1,234.5"},
+		{"fr-x1", "1\u202f234,5"},
+		{"fr-x2", "1\u202f234,5"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", c.accept)
+		w := httptest.NewRecorder()
+		if err := e.Render(w, r, "home", nil); err != nil {
+			t.Fatalf("Render(%s): %v", c.accept, err)
+		}
+		if w.Code != http.StatusOK || w.Body.String() != c.want {
+			t.Fatalf("Render(%s): got %d %q, want 200 %q", c.accept, w.Code, w.Body.String(), c.want)
+		}
+		if got, ok := w.Header()["Content-Language"]; ok {
+			t.Fatalf("Render(%s): unexpected Content-Language %q", c.accept, got)
+		}
+	}
+	want := []string{"frame:home-de", "frame:home-default", "frame:home-en", "frame:home-fr"}
+	if got := e.CachedKeys(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("cached keys: got %v want %v", got, want)
+	}
+}
+
+func TestHTTPRender_NotFound(t *testing.T) {
+	e := newHTTPEngine(map[string]string{})
+
+	w := httptest.NewRecorder()
+	err := e.Render(w, httptest.NewRequest(http.MethodGet, "/", nil), "missing", nil)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected ErrTemplateNotFound, got %v", err)
+	}
+	if w.Code != http.StatusNotFound || w.Body.String() != "Not Found\n" {
+		t.Fatalf("got %d %q, want 404 Not Found", w.Code, w.Body.String())
+	}
+}
+
+func TestHTTPRender_ErrorPage(t *testing.T) {
+	e := newHTTPEngine(map[string]string{
+		"default/fail.tmpl":   "{{.Field}}",
+		"default/page.tmpl":   "page",
+		"default/orphan.tmpl": "{{/* extends \"gone\" */}}",
+	})
+	e.SetErrorPage(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "oops %d", status)
+	})
+
+	w := httptest.NewRecorder()
+	err := e.Render(w, httptest.NewRequest(http.MethodGet, "/", nil), "fail", "string data")
+	if !errors.Is(err, ErrTemplateExecute) {
+		t.Fatalf("expected ErrTemplateExecute, got %v", err)
+	}
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "oops 500" {
+		t.Fatalf("got %d %q, want the custom 500 page", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	var missingLayout *MissingLayoutError
+	if err := e.Render(w, httptest.NewRequest(http.MethodGet, "/", nil), "orphan", nil); !errors.As(err, &missingLayout) {
+		t.Fatalf("expected a *MissingLayoutError, got %v", err)
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("missing layout: got %d want 500", w.Code)
+	}
+
+	e.SetStructTemplateFrames([]string{"_missing_frame"})
+	w = httptest.NewRecorder()
+	if err := e.Render(w, httptest.NewRequest(http.MethodGet, "/", nil), "page", nil); err == nil {
+		t.Fatalf("expected a missing frame error")
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("missing frame: got %d want 500", w.Code)
+	}
+}
+
+func TestHTTPPageWriter_Flush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	page := &pageWriter{ResponseWriter: rec, lang: "ja"}
+
+	if err := http.NewResponseController(page).Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if !rec.Flushed || !page.written {
+		t.Fatalf("expected a flushed, written response")
+	}
+	if got := rec.Header().Get("Content-Language"); got != "ja" {
+		t.Fatalf("Content-Language: got %q want ja", got)
+	}
+	if page.Unwrap() != rec {
+		t.Fatalf("Unwrap did not return the underlying writer")
+	}
+}
+
+func TestHTTPHandler_Data(t *testing.T) {
+	e := newHTTPEngine(map[string]string{"default/user.tmpl": "user {{.}}"})
+
+	handler := e.Handler("user", func(r *http.Request) (any, error) {
+		if id := r.URL.Query().Get("id"); id != "" {
+			return id, nil
+		}
+		return nil, errors.New("no id")
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user?id=7", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "user 7" {
+		t.Fatalf("got %d %q, want 200 %q", w.Code, w.Body.String(), "user 7")
+	}
+	if _, ok := w.Header()["Content-Language"]; ok {
+		t.Fatalf("expected no Content-Language without a language preference")
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("DataFunc error: got %d want 500", w.Code)
+	}
+}
+
+func TestHTTPRender_Streaming(t *testing.T) {
+	e := newHTTPEngine(map[string]string{"default/late.tmpl": "head {{.Field}}"})
+
+	rec := httptest.NewRecorder()
+	if err := e.Render(rec, httptest.NewRequest(http.MethodGet, "/", nil), "late", "x"); !errors.Is(err, ErrTemplateExecute) {
+		t.Fatalf("expected execute error, got %v", err)
+	}
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != http.StatusText(http.StatusInternalServerError)+"\n" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	if err := e.Render(rec, httptest.NewRequest(http.MethodGet, "/", nil), "late", "x", Streaming()); !errors.Is(err, ErrTemplateExecute) {
+		t.Fatalf("expected streaming execute error, got %v", err)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "head " {
+		t.Fatalf("unexpected streaming response %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected Content-Type %q", got)
+	}
+}
@@ -0,0 +1,677 @@
+package kktemplate
+
+import (
+	"bytes"
+	"html"
+	"sort"
+	"strings"
+)
+
+// InlineCSS moves the rules of the <style> blocks of a rendered html page
+// into the style attributes of the elements they select, for mail clients
+// that strip <style>. At-rules such as @media and rules whose selectors
+// cannot be inlined, e.g. a:hover, stay in a plain <style> block in place of
+// the first one. Blocks with attributes other than type="text/css", such as
+// media, are left whole and in place. Type, class, id, attribute and * selectors joined by descendant
+// or child combinators are inlined; the cascade follows specificity and source
+// order, and existing style attributes win over non-important rules.
+// The whole page is buffered even with Streaming.
+func InlineCSS() RenderOption {
+	return func(config *renderConfig) {
+		config.inlineCSS = true
+	}
+}
+
+// htmlTransform is the post-processing of html output requested by config,
+// or nil.
+func (config *renderConfig) htmlTransform() func([]byte) []byte {
+	if config.inlineCSS {
+		return inlineCSS
+	}
+	return nil
+}
+
+type cssDeclaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+type cssRule struct {
+	selector     cssSelector
+	declarations []cssDeclaration
+	order        int
+}
+
+type cssSelector struct {
+	compounds []cssCompound
+	// combinators[i] joins compounds[i-1] and compounds[i]; it is ' ' for
+	// descendant and '>' for child.
+	combinators []byte
+	specificity [3]int
+}
+
+type cssCompound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []cssAttr
+}
+
+type cssAttr struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+type htmlElement struct {
+	name   string
+	attrs  map[string]string
+	parent int
+	// nameEnd is where a new style attribute is inserted; styleStart and
+	// styleEnd span an existing one.
+	nameEnd    int
+	styleStart int
+	styleEnd   int
+}
+
+type styleBlock struct {
+	start, end int
+	css        string
+}
+
+type htmlEdit struct {
+	start, end int
+	text       string
+}
+
+func inlineCSS(page []byte) []byte {
+	elements, blocks := scanHTML(page)
+	if len(blocks) == 0 {
+		return page
+	}
+
+	var rules []cssRule
+	var kept []string
+	for _, block := range blocks {
+		rules, kept = parseCSS(block.css, rules, kept)
+	}
+
+	var edits []htmlEdit
+	for i, block := range blocks {
+		edit := htmlEdit{start: block.start, end: block.end}
+		if i == 0 && len(kept) > 0 {
+			edit.text = "<style>" + strings.Join(kept, "\n") + "</style>"
+		}
+		edits = append(edits, edit)
+	}
+	for i, element := range elements {
+		style, ok := computeStyle(elements, i, rules)
+		if !ok {
+			continue
+		}
+		attr := `style="` + html.EscapeString(style) + `"`
+		if element.styleEnd > element.styleStart {
+			edits = append(edits, htmlEdit{start: element.styleStart, end: element.styleEnd, text: attr})
+		} else {
+			edits = append(edits, htmlEdit{start: element.nameEnd, end: element.nameEnd, text: " " + attr})
+		}
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var out bytes.Buffer
+	out.Grow(len(page))
+	last := 0
+	for _, edit := range edits {
+		out.Write(page[last:edit.start])
+		out.WriteString(edit.text)
+		last = edit.end
+	}
+	out.Write(page[last:])
+	return out.Bytes()
+}
+
+// computeStyle merges the declarations of the rules matching elements[i] with
+// its own style attribute.
+func computeStyle(elements []htmlElement, i int, rules []cssRule) (string, bool) {
+	type ranked struct {
+		cssDeclaration
+		specificity [4]int
+		order       int
+	}
+
+	var declarations []ranked
+	for _, rule := range rules {
+		if !rule.selector.matches(elements, i, len(rule.selector.compounds)-1) {
+			continue
+		}
+		spec := rule.selector.specificity
+		for _, declaration := range rule.declarations {
+			declarations = append(declarations, ranked{declaration, [4]int{0, spec[0], spec[1], spec[2]}, rule.order})
+		}
+	}
+	if len(declarations) == 0 {
+		return "", false
+	}
+	if style, ok := elements[i].attrs["style"]; ok {
+		for _, declaration := range parseDeclarations(style) {
+			declarations = append(declarations, ranked{declaration, [4]int{1, 0, 0, 0}, len(rules)})
+		}
+	}
+
+	sort.SliceStable(declarations, func(a, b int) bool {
+		da, db := declarations[a], declarations[b]
+		if da.important != db.important {
+			return !da.important
+		}
+		if da.specificity != db.specificity {
+			for k := range da.specificity {
+				if da.specificity[k] != db.specificity[k] {
+					return da.specificity[k] < db.specificity[k]
+				}
+			}
+		}
+		return da.order < db.order
+	})
+
+	var properties []string
+	values := map[string]string{}
+	for _, declaration := range declarations {
+		if _, ok := values[declaration.property]; !ok {
+			properties = append(properties, declaration.property)
+		}
+		value := declaration.value
+		if declaration.important {
+			value += " !important"
+		}
+		values[declaration.property] = value
+	}
+	parts := make([]string, len(properties))
+	for k, property := range properties {
+		parts[k] = property + ": " + values[property]
+	}
+	return strings.Join(parts, "; "), true
+}
+
+func (s cssSelector) matches(elements []htmlElement, i int, k int) bool {
+	if !s.compounds[k].matches(elements[i]) {
+		return false
+	}
+	if k == 0 {
+		return true
+	}
+	if s.combinators[k] == '>' {
+		parent := elements[i].parent
+		return parent >= 0 && s.matches(elements, parent, k-1)
+	}
+	for ancestor := elements[i].parent; ancestor >= 0; ancestor = elements[ancestor].parent {
+		if s.matches(elements, ancestor, k-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c cssCompound) matches(element htmlElement) bool {
+	if c.tag != "" && c.tag != element.name {
+		return false
+	}
+	if c.id != "" && element.attrs["id"] != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(element.attrs["class"])
+		for _, class := range c.classes {
+			found := false
+			for _, candidate := range classes {
+				if candidate == class {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attrs {
+		value, ok := element.attrs[attr.name]
+		if !ok || attr.hasValue && value != attr.value {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCSS appends the inlinable rules of css to rules and the css text that
+// must stay in a <style> block to kept.
+func parseCSS(css string, rules []cssRule, kept []string) ([]cssRule, []string) {
+	css = stripCSSComments(css)
+	for pos := 0; pos < len(css); {
+		for pos < len(css) && isCSSSpace(css[pos]) {
+			pos++
+		}
+		if pos >= len(css) {
+			break
+		}
+
+		if css[pos] == '@' {
+			end := scanCSS(css, pos, ";{")
+			if end < len(css) && css[end] == '{' {
+				end = matchBrace(css, end)
+			}
+			kept = append(kept, strings.TrimSpace(css[pos:min(end+1, len(css))]))
+			pos = end + 1
+			continue
+		}
+
+		open := scanCSS(css, pos, "{")
+		if open >= len(css) {
+			break
+		}
+		end := matchBrace(css, open)
+		selectors := strings.TrimSpace(css[pos:open])
+		body := css[open+1 : min(end, len(css))]
+		pos = end + 1
+
+		declarations := parseDeclarations(body)
+		var unsupported []string
+		for _, text := range splitCSS(selectors, ',') {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			selector, ok := parseSelector(text)
+			if !ok {
+				unsupported = append(unsupported, text)
+				continue
+			}
+			rules = append(rules, cssRule{selector: selector, declarations: declarations, order: len(rules)})
+		}
+		if len(unsupported) > 0 {
+			kept = append(kept, strings.Join(unsupported, ", ")+" {"+strings.TrimSpace(body)+"}")
+		}
+	}
+	return rules, kept
+}
+
+func parseDeclarations(body string) []cssDeclaration {
+	var declarations []cssDeclaration
+	for _, text := range splitCSS(body, ';') {
+		property, value, ok := strings.Cut(text, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.TrimSpace(value)
+		if !ok || property == "" || value == "" {
+			continue
+		}
+		declaration := cssDeclaration{property: property, value: value}
+		if bang := strings.LastIndexByte(value, '!'); bang >= 0 && strings.EqualFold(strings.TrimSpace(value[bang+1:]), "important") {
+			declaration.value = strings.TrimSpace(value[:bang])
+			declaration.important = true
+		}
+		declarations = append(declarations, declaration)
+	}
+	return declarations
+}
+
+// parseSelector parses a selector that can be inlined; pseudo-classes,
+// pseudo-elements and sibling combinators cannot.
+func parseSelector(text string) (cssSelector, bool) {
+	var selector cssSelector
+	combinator := byte(0)
+	for pos := 0; pos < len(text); {
+		switch c := text[pos]; {
+		case isCSSSpace(c):
+			if combinator == 0 && len(selector.compounds) > 0 {
+				combinator = ' '
+			}
+			pos++
+			continue
+		case c == '>':
+			if len(selector.compounds) == 0 {
+				return cssSelector{}, false
+			}
+			combinator = '>'
+			pos++
+			continue
+		}
+
+		compound, next, ok := parseCompound(text, pos)
+		if !ok {
+			return cssSelector{}, false
+		}
+		if len(selector.compounds) > 0 && combinator == 0 {
+			return cssSelector{}, false
+		}
+		selector.compounds = append(selector.compounds, compound)
+		selector.combinators = append(selector.combinators, combinator)
+		if compound.id != "" {
+			selector.specificity[0]++
+		}
+		selector.specificity[1] += len(compound.classes) + len(compound.attrs)
+		if compound.tag != "" {
+			selector.specificity[2]++
+		}
+		combinator = 0
+		pos = next
+	}
+	if len(selector.compounds) == 0 || combinator != 0 && combinator != ' ' {
+		return cssSelector{}, false
+	}
+	return selector, true
+}
+
+func parseCompound(text string, pos int) (cssCompound, int, bool) {
+	var compound cssCompound
+	start := pos
+	if pos < len(text) && text[pos] == '*' {
+		pos++
+	} else {
+		name, next := cssIdent(text, pos)
+		compound.tag = strings.ToLower(name)
+		pos = next
+	}
+
+	for pos < len(text) {
+		switch text[pos] {
+		case '.', '#':
+			name, next := cssIdent(text, pos+1)
+			if name == "" {
+				return cssCompound{}, pos, false
+			}
+			if text[pos] == '.' {
+				compound.classes = append(compound.classes, name)
+			} else if compound.id == "" {
+				compound.id = name
+			} else if compound.id != name {
+				return cssCompound{}, pos, false
+			}
+			pos = next
+		case '[':
+			end := strings.IndexByte(text[pos:], ']')
+			if end < 0 {
+				return cssCompound{}, pos, false
+			}
+			attr, ok := parseAttrSelector(text[pos+1 : pos+end])
+			if !ok {
+				return cssCompound{}, pos, false
+			}
+			compound.attrs = append(compound.attrs, attr)
+			pos += end + 1
+		default:
+			if isCSSSpace(text[pos]) || text[pos] == '>' {
+				return compound, pos, pos > start
+			}
+			return cssCompound{}, pos, false
+		}
+	}
+	return compound, pos, pos > start
+}
+
+func parseAttrSelector(text string) (cssAttr, bool) {
+	name, value, hasValue := strings.Cut(text, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if ident, next := cssIdent(name, 0); ident == "" || next != len(name) {
+		return cssAttr{}, false
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return cssAttr{name: name, value: value, hasValue: hasValue}, true
+}
+
+func cssIdent(text string, pos int) (string, int) {
+	start := pos
+	for pos < len(text) {
+		c := text[pos]
+		if c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 {
+			pos++
+			continue
+		}
+		break
+	}
+	return text[start:pos], pos
+}
+
+func stripCSSComments(css string) string {
+	var out strings.Builder
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			out.WriteString(css)
+			return out.String()
+		}
+		out.WriteString(css[:start])
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			return out.String()
+		}
+		css = css[start+2+end+2:]
+	}
+}
+
+// scanCSS returns the index of the first byte of stops in css from pos that
+// is not quoted or within parentheses, or len(css).
+func scanCSS(css string, pos int, stops string) int {
+	depth := 0
+	for ; pos < len(css); pos++ {
+		switch c := css[pos]; {
+		case c == '"' || c == '\'':
+			if end := strings.IndexByte(css[pos+1:], c); end >= 0 {
+				pos += end + 1
+			} else {
+				return len(css)
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0 && strings.IndexByte(stops, c) >= 0:
+			return pos
+		}
+	}
+	return pos
+}
+
+// matchBrace returns the index of the brace closing the one at open, or
+// len(css).
+func matchBrace(css string, open int) int {
+	depth := 0
+	for pos := open; pos < len(css); pos++ {
+		pos = scanCSS(css, pos, "{}")
+		if pos >= len(css) {
+			break
+		}
+		if css[pos] == '{' {
+			depth++
+		} else if depth--; depth == 0 {
+			return pos
+		}
+	}
+	return len(css)
+}
+
+func splitCSS(text string, sep byte) []string {
+	var parts []string
+	for pos := 0; pos <= len(text); {
+		end := scanCSS(text, pos, string(sep))
+		parts = append(parts, text[pos:end])
+		pos = end + 1
+	}
+	return parts
+}
+
+func isCSSSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// scanHTML lists the elements of page with their parents, and its <style>
+// blocks. It is a small tokenizer for rendered pages, not a full html parser:
+// end tags close up to the matching open element and implied end tags are not
+// inferred.
+func scanHTML(page []byte) ([]htmlElement, []styleBlock) {
+	src := string(page)
+	var elements []htmlElement
+	var blocks []styleBlock
+	var stack []int
+
+	for pos := 0; pos < len(src); {
+		lt := strings.IndexByte(src[pos:], '<')
+		if lt < 0 {
+			break
+		}
+		pos += lt
+		rest := src[pos:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return elements, blocks
+			}
+			pos += 4 + end + 3
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			pos = skipPast(src, pos, '>')
+		case strings.HasPrefix(rest, "</"):
+			name, _ := cssIdent(src, pos+2)
+			name = strings.ToLower(name)
+			for i := len(stack) - 1; i >= 0; i-- {
+				if elements[stack[i]].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			pos = skipPast(src, pos, '>')
+		case len(rest) > 1 && isLetter(rest[1]):
+			element, end, selfClosing := scanStartTag(src, pos)
+			if element.name == "style" || element.name == "script" {
+				closeTag := indexFold(src[end:], "</"+element.name)
+				if closeTag < 0 {
+					return elements, blocks
+				}
+				closeEnd := skipPast(src, end+closeTag, '>')
+				if element.name == "style" && plainStyle(element) {
+					blocks = append(blocks, styleBlock{start: pos, end: closeEnd, css: src[end : end+closeTag]})
+				}
+				pos = closeEnd
+				continue
+			}
+
+			element.parent = -1
+			if len(stack) > 0 {
+				element.parent = stack[len(stack)-1]
+			}
+			elements = append(elements, element)
+			if !selfClosing && !voidElements[element.name] {
+				stack = append(stack, len(elements)-1)
+			}
+			pos = end
+		default:
+			pos++
+		}
+	}
+	return elements, blocks
+}
+
+// plainStyle reports whether a <style> element applies to every medium and
+// context, so its rules can be inlined: it has no attributes other than a
+// text/css type.
+func plainStyle(element htmlElement) bool {
+	for name, value := range element.attrs {
+		if name != "type" || (value != "" && !strings.EqualFold(strings.TrimSpace(value), "text/css")) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanStartTag reads the start tag at pos and returns its element, the index
+// after the tag and whether it is self-closing.
+func scanStartTag(src string, pos int) (htmlElement, int, bool) {
+	name, nameEnd := cssIdent(src, pos+1)
+	element := htmlElement{name: strings.ToLower(name), attrs: map[string]string{}, nameEnd: nameEnd}
+	selfClosing := false
+
+	for p := nameEnd; p < len(src); {
+		c := src[p]
+		switch {
+		case c == '>':
+			return element, p + 1, selfClosing
+		case c == '/':
+			selfClosing = true
+			p++
+			continue
+		case isCSSSpace(c):
+			p++
+			continue
+		}
+
+		selfClosing = false
+		attrStart := p
+		for p < len(src) && !isCSSSpace(src[p]) && src[p] != '=' && src[p] != '>' && !(src[p] == '/' && p+1 < len(src) && src[p+1] == '>') {
+			p++
+		}
+		attrName := strings.ToLower(src[attrStart:p])
+		value := ""
+		q := p
+		for q < len(src) && isCSSSpace(src[q]) {
+			q++
+		}
+		if q < len(src) && src[q] == '=' {
+			q++
+			for q < len(src) && isCSSSpace(src[q]) {
+				q++
+			}
+			if q < len(src) && (src[q] == '"' || src[q] == '\'') {
+				end := strings.IndexByte(src[q+1:], src[q])
+				if end < 0 {
+					return element, len(src), false
+				}
+				value = src[q+1 : q+1+end]
+				p = q + 1 + end + 1
+			} else {
+				start := q
+				for q < len(src) && !isCSSSpace(src[q]) && src[q] != '>' {
+					q++
+				}
+				value = src[start:q]
+				p = q
+			}
+		}
+		if _, ok := element.attrs[attrName]; !ok {
+			element.attrs[attrName] = html.UnescapeString(value)
+			if attrName == "style" {
+				element.styleStart, element.styleEnd = attrStart, p
+			}
+		}
+	}
+	return element, len(src), selfClosing
+}
+
+func skipPast(src string, pos int, c byte) int {
+	if end := strings.IndexByte(src[pos:], c); end >= 0 {
+		return pos + end + 1
+	}
+	return len(src)
+}
+
+func indexFold(s string, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
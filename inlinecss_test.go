@@ -0,0 +1,103 @@
+// inlinecss_test.go contains unit tests for the CSS inliner.
+//
+// Test Case Index:
+// - TestInlineCSS_Cascade: matching rules are inlined by specificity and source order, with existing style attributes and !important honored.
+// - TestInlineCSS_Selectors: descendant, child, attribute and compound selectors match the right elements.
+// - TestInlineCSS_KeepsAtRules: @media rules and pseudo-class selectors stay in a single plain <style> block in place of the first one.
+// - TestInlineCSS_AttributedBlocks: <style> blocks with media or other attributes are left whole and in place, and are not inlined.
+// - TestRenderFrame_InlineCSS: InlineCSS inlines the styles of the _header_content frame and gives identical output on every render.
+package kktemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInlineCSS_Cascade(t *testing.T) {
+	page := `<style>
+p { color: red; margin: 0 }
+.note { color: blue }
+p { font-family: "Helvetica", sans-serif }
+#lead { color: green }
+p.note { padding: 1px !important }
+</style><p class="note" id="lead" style="padding: 2px; color: black">a</p><p class="note">b</p><p>c</p>`
+
+	want := `<p class="note" id="lead" style="color: black; margin: 0; font-family: &#34;Helvetica&#34;, sans-serif; padding: 1px !important">a</p>` +
+		`<p style="color: blue; margin: 0; font-family: &#34;Helvetica&#34;, sans-serif; padding: 1px !important" class="note">b</p>` +
+		`<p style="color: red; margin: 0; font-family: &#34;Helvetica&#34;, sans-serif">c</p>`
+	if got := string(inlineCSS([]byte(page))); got != want {
+		t.Fatalf("output mismatch:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestInlineCSS_Selectors(t *testing.T) {
+	page := `<style>
+table td { padding: 4px }
+tr > td.x { color: red }
+a[href] { color: blue }
+a[target="_blank"] { text-decoration: none }
+* { box-sizing: border-box }
+</style><table><tr><td class="x"><a href="/">l</a><br></td></tr></table><a target="_blank">t</a>`
+
+	want := `<table style="box-sizing: border-box"><tr style="box-sizing: border-box">` +
+		`<td style="box-sizing: border-box; padding: 4px; color: red" class="x">` +
+		`<a style="box-sizing: border-box; color: blue" href="/">l</a><br style="box-sizing: border-box"></td></tr></table>` +
+		`<a style="box-sizing: border-box; text-decoration: none" target="_blank">t</a>`
+	if got := string(inlineCSS([]byte(page))); got != want {
+		t.Fatalf("output mismatch:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestInlineCSS_KeepsAtRules(t *testing.T) {
+	page := `<html><head><style type="text/css">
+/* base */
+a { color: red }
+a:hover, b { color: blue }
+@media (max-width: 600px) { a { color: green } }
+</style></head><body><style>b { margin: 0 }</style><a>x</a><b>y</b></body></html>`
+
+	want := `<html><head><style>a:hover {color: blue}
+@media (max-width: 600px) { a { color: green } }</style></head>` +
+		`<body><a style="color: red">x</a><b style="color: blue; margin: 0">y</b></body></html>`
+	if got := string(inlineCSS([]byte(page))); got != want {
+		t.Fatalf("output mismatch:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestInlineCSS_AttributedBlocks(t *testing.T) {
+	page := `<head><style media="print">a { color: black }</style><style nonce="n1">b { color: blue }</style>` +
+		`<style TYPE="text/css">a { color: red } a:hover { color: green }</style></head><a>x</a><b>y</b>`
+
+	want := `<head><style media="print">a { color: black }</style><style nonce="n1">b { color: blue }</style>` +
+		`<style>a:hover {color: green}</style></head><a style="color: red">x</a><b>y</b>`
+	if got := string(inlineCSS([]byte(page))); got != want {
+		t.Fatalf("output mismatch:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestRenderFrame_InlineCSS(t *testing.T) {
+	e := newHTTPEngine(map[string]string{
+		"default/_header_content.tmpl": "<style>.hi { color: red } @media print { .hi { color: black } }</style>",
+		"default/mail.tmpl":            `<html><head>{{template "_header_content.tmpl" .}}</head><body><p class="hi">{{.}}</p></body></html>`,
+	})
+
+	want := `<html><head><style>@media print { .hi { color: black } }</style></head><body><p style="color: red" class="hi">Ann</p></body></html>`
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		if err := e.RenderFrame(&buf, "mail", "en", "Ann", InlineCSS()); err != nil {
+			t.Fatalf("RenderFrame: %v", err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("render %d mismatch:\ngot  %s\nwant %s", i, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := e.RenderFrame(&buf, "mail", "en", "Ann"); err != nil {
+		t.Fatalf("RenderFrame: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<style>.hi") {
+		t.Fatalf("styles inlined without InlineCSS: %s", buf.String())
+	}
+}
@@ -3,9 +3,12 @@ package kktemplate
 import (
 	"fmt"
 	html "html/template"
+	"io/fs"
 	"os"
+	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	text "text/template"
 
 	"github.com/yetiz-org/goth-kklogger"
@@ -15,17 +18,34 @@ import (
 var TemplateRootPath = "./resources/template"
 var StructTemplateFrames = []string{"_main", "_header_content", "_header_claim", "_footer_content", "_footer_claim"}
 var FuncMap = html.FuncMap{}
+
+// LangFallback, when set, replaces DefaultLangFallback for the default engine.
+var LangFallback LangFallbackFunc
+
+// TemplateFS, when set, is used as the template source of the default engine
+// instead of the local directory at TemplateRootPath.
+var TemplateFS fs.FS
+
+// PartialsDir, when set, is the directory inside each language directory
+// whose templates the default engine parses into every template it loads.
+var PartialsDir = ""
 var ErrTemplateNotFound = fmt.Errorf("template file not found")
+var ErrInvalidEngine = fmt.Errorf("invalid engine")
 
 var htmlTemplateMap, frameHtmlTemplateMap = map[string]*html.Template{}, map[string]*html.Template{}
 var textTemplateMap = map[string]*text.Template{}
 var htmlLocker, textLocker, frameLocker = sync.Mutex{}, sync.Mutex{}, sync.Mutex{}
-var frameExist = false
+
+// frameExist records, per language, that the struct template frames resolve.
+var frameExist = map[string]bool{}
 
 type Engine struct {
 	templateRootPath     string
 	structTemplateFrames []string
 	funcMap              html.FuncMap
+	templateFS           fs.FS
+	langFallback         LangFallbackFunc
+	partialsDir          string
 
 	htmlTemplateMap      *map[string]*html.Template
 	frameHtmlTemplateMap *map[string]*html.Template
@@ -35,7 +55,34 @@ type Engine struct {
 	textLocker  *sync.Mutex
 	frameLocker *sync.Mutex
 
-	frameExist *bool
+	frameExist *map[string]bool
+
+	// frameSets and frameSetExist are guarded by frameLocker,
+	// frameSetTemplateMap by htmlLocker.
+	frameSets           map[string][]string
+	frameSetExist       map[string]map[string]bool
+	frameSetTemplateMap map[string]*html.Template
+
+	// htmlTemplateCopies, guarded by htmlLocker, and textTemplateCopies,
+	// guarded by textLocker, hold the copies of each cached template.
+	htmlTemplateCopies map[*html.Template]*htmlCopies
+	textTemplateCopies map[*text.Template]*textCopies
+
+	entryLocker  sync.Mutex
+	cacheEntries map[string]cacheEntry
+	watchLocker  sync.Mutex
+	watcher      *templateWatcher
+
+	httpLocker sync.RWMutex
+	errorPage  ErrorPageFunc
+
+	factoryLocker sync.RWMutex
+	funcFactories map[string]FuncFactory
+	requestFuncs  map[string]any
+
+	// debugMode holds the value set by SetDebug: 0 while unset, 1 on, 2 off.
+	debugMode atomic.Int32
+	getDebug  func() bool
 
 	getTemplateRootPath     func() string
 	setTemplateRootPath     func(string)
@@ -43,6 +90,12 @@ type Engine struct {
 	setStructTemplateFrames func([]string)
 	getFuncMap              func() html.FuncMap
 	setFuncMap              func(html.FuncMap)
+	getTemplateFS           func() fs.FS
+	setTemplateFS           func(fs.FS)
+	getLangFallback         func() LangFallbackFunc
+	setLangFallback         func(LangFallbackFunc)
+	getPartialsDir          func() string
+	setPartialsDir          func(string)
 }
 
 var defaultEngine = newDefaultEngine()
@@ -74,6 +127,25 @@ func newDefaultEngine() *Engine {
 		setFuncMap: func(fm html.FuncMap) {
 			FuncMap = fm
 		},
+		getTemplateFS: func() fs.FS {
+			return TemplateFS
+		},
+		setTemplateFS: func(fsys fs.FS) {
+			TemplateFS = fsys
+		},
+		getLangFallback: func() LangFallbackFunc {
+			return LangFallback
+		},
+		setLangFallback: func(fn LangFallbackFunc) {
+			LangFallback = fn
+		},
+		getPartialsDir: func() string {
+			return PartialsDir
+		},
+		setPartialsDir: func(dir string) {
+			PartialsDir = dir
+		},
+		getDebug: _IsDebug,
 	}
 }
 
@@ -88,7 +160,7 @@ func New() *Engine {
 	htmlMu := &sync.Mutex{}
 	textMu := &sync.Mutex{}
 	frameMu := &sync.Mutex{}
-	frameExists := false
+	frameExists := map[string]bool{}
 	return &Engine{
 		templateRootPath:     "./resources/template",
 		structTemplateFrames: []string{"_main", "_header_content", "_header_claim", "_footer_content", "_footer_claim"},
@@ -136,6 +208,21 @@ func (e *Engine) SetFuncMap(fm html.FuncMap) {
 	e.funcMap = fm
 }
 
+// SetTemplateFS makes the engine read templates from fsys. The root of fsys is
+// the template root, so the language directories must sit at its top level
+// (use fs.Sub to strip a prefix, e.g. for an embed.FS). A nil fsys restores
+// loading from the local directory at the template root path.
+func (e *Engine) SetTemplateFS(fsys fs.FS) {
+	if e == nil {
+		return
+	}
+	if e.setTemplateFS != nil {
+		e.setTemplateFS(fsys)
+		return
+	}
+	e.templateFS = fsys
+}
+
 func (e *Engine) templateRootPathValue() string {
 	if e == nil {
 		return ""
@@ -166,158 +253,270 @@ func (e *Engine) funcMapValue() html.FuncMap {
 	return e.funcMap
 }
 
+func (e *Engine) templateFSValue() fs.FS {
+	if e == nil {
+		return nil
+	}
+	if e.getTemplateFS != nil {
+		return e.getTemplateFS()
+	}
+	return e.templateFS
+}
+
+// sourceFS returns the file system every template lookup goes through.
+func (e *Engine) sourceFS() fs.FS {
+	if fsys := e.templateFSValue(); fsys != nil {
+		return fsys
+	}
+	root := e.templateRootPathValue()
+	if root == "" {
+		root = "."
+	}
+	return os.DirFS(root)
+}
+
 func LoadHtml(name string, lang string) (*html.Template, error) {
 	return defaultEngine.LoadHtml(name, lang)
 }
 
 func (e *Engine) LoadHtml(name string, lang string) (*html.Template, error) {
+	tmpl, _, err := e.loadHtml(name, lang)
+	return tmpl, err
+}
+
+func (e *Engine) loadHtml(name string, lang string) (*html.Template, *htmlCopies, error) {
 	if e == nil || e.htmlTemplateMap == nil || e.htmlLocker == nil {
-		return nil, fmt.Errorf("invalid engine")
+		return nil, nil, ErrInvalidEngine
 	}
 	mapName := name + "-" + lang
 	if e.isDebug() {
 		e.htmlLocker.Lock()
-		delete(*e.htmlTemplateMap, mapName)
+		dropTemplate(e.htmlTemplateCopies, *e.htmlTemplateMap, mapName)
 		e.htmlLocker.Unlock()
 	}
 
 	e.htmlLocker.Lock()
-	tmpl := (*e.htmlTemplateMap)[mapName]
+	tmpl, copies := cachedTemplate(e.htmlTemplateCopies, *e.htmlTemplateMap, mapName)
 	e.htmlLocker.Unlock()
-	if tmpl != nil {
-		return tmpl, nil
+	if copies != nil {
+		return tmpl, copies, nil
 	}
 
-	data := func() []byte {
-		if data, err := os.ReadFile(e.getRealTemplatePath(name, lang)); !os.IsNotExist(err) {
-			return data
-		}
-		return nil
-	}()
-	if data == nil {
-		return nil, ErrTemplateNotFound
+	parsed, chain, err := e.parseHtml(name, lang)
+	if err != nil {
+		return nil, nil, err
 	}
+	if copies, err = e.newHTMLCopies(parsed); err != nil {
+		return nil, nil, err
+	}
+
+	e.htmlLocker.Lock()
+	tmpl, copies, stored := storeTemplate(&e.htmlTemplateCopies, *e.htmlTemplateMap, mapName, parsed, copies)
+	e.htmlLocker.Unlock()
+	if stored {
+		e.trackCache(cacheEntry{kind: cacheHTML, name: name, lang: lang, deps: layoutChainNames(chain)})
+	}
+	return tmpl, copies, nil
+}
 
-	parsed, err := html.New(mapName).Funcs(e.generateHTMLFuncMap(lang)).Parse(string(data))
+// parseHtml parses the layout chain of name for lang with the partials.
+func (e *Engine) parseHtml(name string, lang string) (*html.Template, []layoutFile, error) {
+	chain, err := e.layoutChain(name, lang)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	e.htmlLocker.Lock()
-	if existing := (*e.htmlTemplateMap)[mapName]; existing != nil {
-		e.htmlLocker.Unlock()
-		return existing, nil
+	parsed := html.New(name).Funcs(e.generateHTMLFuncMap(lang))
+	if err := parsePartials(parsed, e.partialFiles(lang), name); err != nil {
+		return nil, nil, err
 	}
-	(*e.htmlTemplateMap)[mapName] = parsed
-	e.htmlLocker.Unlock()
-	return parsed, nil
+	if err := parseLayoutChain(parsed, name, chain); err != nil {
+		return nil, nil, err
+	}
+	return parsed, chain, nil
 }
 
 func LoadFrameHtml(name string, lang string) (*html.Template, error) {
 	return defaultEngine.LoadFrameHtml(name, lang)
 }
 
+// LoadFrameHtml loads name composed with the struct template frames. The
+// returned template is named name and executes the page, so Execute renders
+// it without knowing any file name; frames are called by their logical names,
+// e.g. {{template "_main" .}}.
 func (e *Engine) LoadFrameHtml(name string, lang string) (*html.Template, error) {
+	tmpl, _, err := e.loadFrameHtml(name, lang)
+	return tmpl, err
+}
+
+func (e *Engine) loadFrameHtml(name string, lang string) (*html.Template, *htmlCopies, error) {
 	if e == nil || e.frameHtmlTemplateMap == nil || e.htmlLocker == nil {
-		return nil, fmt.Errorf("invalid engine")
+		return nil, nil, ErrInvalidEngine
 	}
 	mapName := name + "-" + lang
 	if e.isDebug() {
 		e.htmlLocker.Lock()
-		delete(*e.frameHtmlTemplateMap, mapName)
+		dropTemplate(e.htmlTemplateCopies, *e.frameHtmlTemplateMap, mapName)
 		e.htmlLocker.Unlock()
 	}
 
 	e.htmlLocker.Lock()
-	tmpl := (*e.frameHtmlTemplateMap)[mapName]
+	tmpl, copies := cachedTemplate(e.htmlTemplateCopies, *e.frameHtmlTemplateMap, mapName)
 	e.htmlLocker.Unlock()
-	if tmpl != nil {
-		return tmpl, nil
+	if copies != nil {
+		return tmpl, copies, nil
 	}
 
-	if !e.frameExistValidate() {
-		return nil, ErrTemplateNotFound
+	if err := e.frameExistValidate(lang); err != nil {
+		return nil, nil, err
 	}
 
-	tmplPath := e.getRealTemplatePath(name, lang)
-	if tmplPath == "" {
-		return nil, ErrTemplateNotFound
+	frames := e.structTemplateFramesValue()
+	parsed, chain, err := e.parseFrameHtml(name, lang, frames)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	if _, err := os.ReadFile(tmplPath); os.IsNotExist(err) {
-		return nil, ErrTemplateNotFound
+	if copies, err = e.newHTMLCopies(parsed); err != nil {
+		return nil, nil, err
 	}
 
-	filePaths := make([]string, 0, 1+len(e.structTemplateFramesValue()))
-	filePaths = append(filePaths, tmplPath)
-	for _, structFrame := range e.structTemplateFramesValue() {
-		filePaths = append(filePaths, e.getRealTemplatePath(structFrame, lang))
+	e.htmlLocker.Lock()
+	tmpl, copies, stored := storeTemplate(&e.htmlTemplateCopies, *e.frameHtmlTemplateMap, mapName, parsed, copies)
+	e.htmlLocker.Unlock()
+	if stored {
+		e.trackCache(cacheEntry{kind: cacheFrame, name: name, lang: lang, deps: append(layoutChainNames(chain), frames...)})
 	}
+	return tmpl, copies, nil
+}
 
-	parsed, err := html.New(tmplPath).Funcs(e.generateHTMLFuncMap(lang)).ParseFiles(filePaths...)
+// parseFrameHtml parses the layout chain of name for lang together with
+// frames. The page is the entry point, named name; each frame is named after
+// its logical name. The page and the frames are also reachable as
+// "<base name>.tmpl", the names they had when frame pages were parsed as
+// files.
+func (e *Engine) parseFrameHtml(name string, lang string, frames []string) (*html.Template, []layoutFile, error) {
+	chain, err := e.layoutChain(name, lang)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	e.htmlLocker.Lock()
-	if existing := (*e.frameHtmlTemplateMap)[mapName]; existing != nil {
-		e.htmlLocker.Unlock()
-		return existing, nil
+	parsed := html.New(name).Funcs(e.generateHTMLFuncMap(lang))
+	if err := parsePartials(parsed, e.partialFiles(lang), name); err != nil {
+		return nil, nil, err
 	}
-	(*e.frameHtmlTemplateMap)[mapName] = parsed
-	e.htmlLocker.Unlock()
-	return parsed, nil
+	if err := parseLayoutChain(parsed, name, chain); err != nil {
+		return nil, nil, err
+	}
+	for _, frame := range frames {
+		framePath := e.getRealTemplatePath(frame, lang)
+		data := e.readTemplate(framePath)
+		if data == nil {
+			return nil, nil, &MissingFrameError{Frame: frame, Lang: lang}
+		}
+		if _, err := parsed.New(frame).Parse(string(data)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, logical := range append([]string{name}, frames...) {
+		alias := path.Base(logical) + ".tmpl"
+		if parsed.Lookup(alias) != nil {
+			continue
+		}
+		if _, err := parsed.AddParseTree(alias, parsed.Lookup(logical).Tree); err != nil {
+			return nil, nil, err
+		}
+	}
+	return parsed, chain, nil
 }
 
+// getRealTemplatePath resolves name for lang to a path inside sourceFS by
+// walking langChain. It returns "" when no candidate exists.
 func (e *Engine) getRealTemplatePath(name string, lang string) string {
-	tmplPath := fmt.Sprintf("%s/%s/%s.tmpl", e.templateRootPathValue(), lang, name)
-	if _, err := os.Stat(tmplPath); !os.IsNotExist(err) {
-		return tmplPath
+	fsys := e.sourceFS()
+	for _, candidate := range e.LangChain(lang) {
+		if tmplPath := path.Join(candidate, name+".tmpl"); e.templateExists(fsys, tmplPath) {
+			return tmplPath
+		}
 	}
 
-	ml := func() string {
-		if slang := strings.Split(lang, "-"); len(slang) > 1 {
-			return slang[0]
-		}
-		return ""
-	}()
+	return ""
+}
 
-	tmplPath = fmt.Sprintf("%s/%s/%s.tmpl", e.templateRootPathValue(), ml, name)
-	if _, err := os.Stat(tmplPath); !os.IsNotExist(err) {
-		return tmplPath
+func (e *Engine) templateExists(fsys fs.FS, tmplPath string) bool {
+	if !fs.ValidPath(tmplPath) {
+		return false
 	}
+	_, err := fs.Stat(fsys, tmplPath)
+	return err == nil
+}
 
-	tmplPath = fmt.Sprintf("%s/default/%s.tmpl", e.templateRootPathValue(), name)
-	if _, err := os.Stat(tmplPath); !os.IsNotExist(err) {
-		return tmplPath
+// readTemplate returns the content of tmplPath in sourceFS, or nil when it
+// cannot be read.
+func (e *Engine) readTemplate(tmplPath string) []byte {
+	if tmplPath == "" {
+		return nil
 	}
-
-	return ""
+	data, err := fs.ReadFile(e.sourceFS(), tmplPath)
+	if err != nil {
+		return nil
+	}
+	return data
 }
 
-func (e *Engine) frameExistValidate() bool {
+// frameExistValidate checks that every struct template frame resolves for
+// lang. A success is remembered until the frames are invalidated, except in
+// debug mode.
+func (e *Engine) frameExistValidate(lang string) error {
 	if e == nil || e.frameExist == nil || e.frameLocker == nil {
-		return false
+		return ErrInvalidEngine
 	}
 	e.frameLocker.Lock()
 	defer e.frameLocker.Unlock()
-	if *e.frameExist {
-		return true
+	if (*e.frameExist)[lang] && !e.isDebug() {
+		return nil
 	}
-	for _, frame := range e.structTemplateFramesValue() {
-		framePath := e.getRealTemplatePath(frame, "")
-		if framePath == "" {
-			kklogger.ErrorJ("kktemplate:_FrameExistValidate", fmt.Sprintf("frame file %s/%s.tmpl is not exist", e.templateRootPathValue(), frame))
-			return false
-		}
+	if err := e.checkFrames("", e.structTemplateFramesValue(), lang); err != nil {
+		kklogger.ErrorJ("kktemplate:_FrameExistValidate", err.Error())
+		return err
+	}
+	if *e.frameExist == nil {
+		*e.frameExist = map[string]bool{}
+	}
+	(*e.frameExist)[lang] = true
+	return nil
+}
+
+// MissingFrameError reports a frame that does not resolve for Lang. It
+// matches ErrTemplateNotFound with errors.Is.
+type MissingFrameError struct {
+	Frame string
+	Lang  string
+	// Set is the frame set the frame belongs to, or "" for the struct
+	// template frames.
+	Set string
+}
 
-		if _, err := os.Stat(framePath); os.IsNotExist(err) {
-			kklogger.ErrorJ("kktemplate:_FrameExistValidate", fmt.Sprintf("frame file %s/%s.tmpl is not exist", e.templateRootPathValue(), frame))
-			return false
+func (e *MissingFrameError) Error() string {
+	if e.Set != "" {
+		return fmt.Sprintf("frame %s of frame set %s is missing for lang %q", e.Frame, e.Set, e.Lang)
+	}
+	return fmt.Sprintf("frame %s is missing for lang %q", e.Frame, e.Lang)
+}
+
+func (e *MissingFrameError) Unwrap() error {
+	return ErrTemplateNotFound
+}
+
+// checkFrames returns a *MissingFrameError for the first of frames that does
+// not resolve through the language chain of lang.
+func (e *Engine) checkFrames(set string, frames []string, lang string) error {
+	for _, frame := range frames {
+		if e.getRealTemplatePath(frame, lang) == "" {
+			return &MissingFrameError{Frame: frame, Lang: lang, Set: set}
 		}
 	}
-	*e.frameExist = true
-	return true
+	return nil
 }
 
 func LoadText(name string, lang string) (*text.Template, error) {
@@ -325,46 +524,51 @@ func LoadText(name string, lang string) (*text.Template, error) {
 }
 
 func (e *Engine) LoadText(name string, lang string) (*text.Template, error) {
+	tmpl, _, err := e.loadText(name, lang)
+	return tmpl, err
+}
+
+func (e *Engine) loadText(name string, lang string) (*text.Template, *textCopies, error) {
 	if e == nil || e.textTemplateMap == nil || e.textLocker == nil {
-		return nil, fmt.Errorf("invalid engine")
+		return nil, nil, ErrInvalidEngine
 	}
 	mapName := name + "-" + lang
 	if e.isDebug() {
 		e.textLocker.Lock()
-		delete(*e.textTemplateMap, mapName)
+		dropTemplate(e.textTemplateCopies, *e.textTemplateMap, mapName)
 		e.textLocker.Unlock()
 	}
 
 	e.textLocker.Lock()
-	tmpl := (*e.textTemplateMap)[mapName]
+	tmpl, copies := cachedTemplate(e.textTemplateCopies, *e.textTemplateMap, mapName)
 	e.textLocker.Unlock()
-	if tmpl != nil {
-		return tmpl, nil
+	if copies != nil {
+		return tmpl, copies, nil
 	}
 
-	data := func() []byte {
-		if data, err := os.ReadFile(e.getRealTemplatePath(name, lang)); !os.IsNotExist(err) {
-			return data
-		}
-		return nil
-	}()
-	if data == nil {
-		return nil, ErrTemplateNotFound
+	chain, err := e.layoutChain(name, lang)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	parsed, err := text.New(mapName).Funcs(e.generateTEXTFuncMap(lang)).Parse(string(data))
-	if err != nil {
-		return nil, err
+	parsed := text.New(name).Funcs(e.generateTEXTFuncMap(lang))
+	if err := parsePartials(parsed, e.partialFiles(lang), name); err != nil {
+		return nil, nil, err
+	}
+	if err := parseLayoutChain(parsed, name, chain); err != nil {
+		return nil, nil, err
+	}
+	if copies, err = e.newTextCopies(parsed); err != nil {
+		return nil, nil, err
 	}
 
 	e.textLocker.Lock()
-	if existing := (*e.textTemplateMap)[mapName]; existing != nil {
-		e.textLocker.Unlock()
-		return existing, nil
-	}
-	(*e.textTemplateMap)[mapName] = parsed
+	tmpl, copies, stored := storeTemplate(&e.textTemplateCopies, *e.textTemplateMap, mapName, parsed, copies)
 	e.textLocker.Unlock()
-	return parsed, nil
+	if stored {
+		e.trackCache(cacheEntry{kind: cacheText, name: name, lang: lang, deps: layoutChainNames(chain)})
+	}
+	return tmpl, copies, nil
 }
 
 func _IsDebug() bool {
@@ -375,30 +579,91 @@ func _IsDebug() bool {
 	return strings.ToUpper(v) == "TRUE"
 }
 
+// SetDebug switches debug mode, in which every load parses the template
+// again instead of using the cache. It is safe to call while the engine is
+// serving. Default() follows APP_DEBUG/KKAPP_DEBUG until SetDebug is called;
+// engines from New() start with debug off.
+func (e *Engine) SetDebug(debug bool) {
+	if e == nil {
+		return
+	}
+	if debug {
+		e.debugMode.Store(1)
+	} else {
+		e.debugMode.Store(2)
+	}
+}
+
+func (e *Engine) IsDebug() bool {
+	return e.isDebug()
+}
+
 func (e *Engine) isDebug() bool {
-	return _IsDebug()
+	if e == nil {
+		return false
+	}
+	switch e.debugMode.Load() {
+	case 1:
+		return true
+	case 2:
+		return false
+	}
+	if e.getDebug != nil {
+		return e.getDebug()
+	}
+	return false
 }
 
 func (e *Engine) generateHTMLFuncMap(lang string) html.FuncMap {
 	funcMap := html.FuncMap{
-		"T": func(str string) string { return kktranslation.GetLangFile(lang).T(str) },
+		"T":  func(str string) string { return kktranslation.GetLangFile(lang).T(str) },
+		"Tf": func(key string, args ...any) html.HTML { return html.HTML(translateFormat(lang, key, args, true)) },
+		"Tn": func(key string, count any, args ...any) html.HTML {
+			return html.HTML(translatePlural(lang, key, count, args, true))
+		},
+	}
+	for k, v := range localeFuncs(lang) {
+		funcMap[k] = v
+	}
+	for k, v := range componentPlaceholders() {
+		funcMap[k] = v
+	}
+	for k, v := range e.requestFuncPlaceholders() {
+		funcMap[k] = v
 	}
 
 	for k, v := range e.funcMapValue() {
 		funcMap[k] = v
 	}
+	for k, v := range e.boundFuncs(lang) {
+		funcMap[k] = v
+	}
 
 	return funcMap
 }
 
 func (e *Engine) generateTEXTFuncMap(lang string) text.FuncMap {
 	funcMap := text.FuncMap{
-		"T": func(str string) string { return kktranslation.GetLangFile(lang).T(str) },
+		"T":  func(str string) string { return kktranslation.GetLangFile(lang).T(str) },
+		"Tf": func(key string, args ...any) string { return translateFormat(lang, key, args, false) },
+		"Tn": func(key string, count any, args ...any) string { return translatePlural(lang, key, count, args, false) },
+	}
+	for k, v := range localeFuncs(lang) {
+		funcMap[k] = v
+	}
+	for k, v := range componentPlaceholders() {
+		funcMap[k] = v
+	}
+	for k, v := range e.requestFuncPlaceholders() {
+		funcMap[k] = v
 	}
 
 	for k, v := range e.funcMapValue() {
 		funcMap[k] = v
 	}
+	for k, v := range e.boundFuncs(lang) {
+		funcMap[k] = v
+	}
 
 	return funcMap
 }
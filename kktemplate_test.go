@@ -11,16 +11,25 @@
  // - TestLoadText_NotFound: LoadText returns ErrTemplateNotFound when the requested template does not exist.
  // - TestLoadText_Cache_Debug: LoadText reloads templates on each call when KKAPP_DEBUG is enabled.
  // - TestLoadText_FuncMap: LoadText applies the global FuncMap when parsing templates.
- // - TestLoadFrameHtml_NotFound_WhenFrameMissing: LoadFrameHtml returns ErrTemplateNotFound if required frame templates are missing.
+ // - TestLoadFrameHtml_NotFound_WhenFrameMissing: LoadFrameHtml returns a *MissingFrameError matching ErrTemplateNotFound if required frame templates are missing.
  // - TestLoadFrameHtml_Basic: LoadFrameHtml loads the page template with frame templates and executes the composed output.
+ // - TestLoadFrameHtml_StableNames: every loader names templates by logical name, and frame pages execute via Execute with .tmpl aliases kept.
+ // - TestLoadFrameHtml_PerLangValidation: frames are validated per language, through its fallback chain, and checked again after invalidation.
+ // - TestEngine_TemplateFS_Fallback: an Engine backed by an fs.FS applies the lang -> base language -> default fallback.
+ // - TestEngine_TemplateFS_Frame: an Engine backed by an fs.FS validates and composes frame templates.
+ // - TestTemplateFS_DefaultEngine: setting TemplateFS switches the package-level loaders to the fs.FS source.
+ // - TestEngine_Debug_PerEngine: two engines over the same files honour their own debug setting.
+ // - TestEngine_Debug_Env: only Default() follows APP_DEBUG/KKAPP_DEBUG; New() engines start with debug off.
 package kktemplate
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/yetiz-org/goth-kktranslation"
 
@@ -72,13 +81,19 @@ func resetGlobals(t *testing.T, newRoot string) {
 	oldText := textTemplateMap
 	oldFrameExist := frameExist
 	oldFuncMap := FuncMap
+	oldTemplateFS := TemplateFS
+	oldLangFallback := LangFallback
+	oldPartialsDir := PartialsDir
 
 	TemplateRootPath = newRoot
 	htmlTemplateMap = map[string]*html.Template{}
 	frameHtmlTemplateMap = map[string]*html.Template{}
 	textTemplateMap = map[string]*text.Template{}
-	frameExist = false
+	frameExist = map[string]bool{}
 	FuncMap = html.FuncMap{}
+	TemplateFS = nil
+	LangFallback = nil
+	PartialsDir = ""
 
 	t.Cleanup(func() {
 		TemplateRootPath = oldRoot
@@ -87,6 +102,9 @@ func resetGlobals(t *testing.T, newRoot string) {
 		textTemplateMap = oldText
 		frameExist = oldFrameExist
 		FuncMap = oldFuncMap
+		TemplateFS = oldTemplateFS
+		LangFallback = oldLangFallback
+		PartialsDir = oldPartialsDir
 	})
 }
 
@@ -393,9 +411,13 @@ func TestLoadFrameHtml_NotFound_WhenFrameMissing(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected error")
 	}
-	if err != ErrTemplateNotFound {
+	if !errors.Is(err, ErrTemplateNotFound) {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	var missing *MissingFrameError
+	if !errors.As(err, &missing) || missing.Frame != StructTemplateFrames[0] || missing.Lang != "en-US" {
+		t.Fatalf("expected the first missing frame for en-US, got %v", err)
+	}
 }
 
  // TestLoadFrameHtml_Basic verifies that LoadFrameHtml loads all required frame templates and the
@@ -556,3 +578,207 @@ func TestLoadFrameHtml_TranslateFunc_Basic(t *testing.T) {
 		t.Fatalf("output mismatch: got %q want %q", got, want)
 	}
 }
+
+ // TestEngine_TemplateFS_Fallback verifies that an Engine reading from an fs.FS resolves
+ // templates through the same lang -> base language -> default chain as the disk loader.
+func TestEngine_TemplateFS_Fallback(t *testing.T) {
+	e := New()
+	e.SetTemplateFS(fstest.MapFS{
+		"zh/hello.tmpl":      {Data: []byte("zh")},
+		"default/hello.tmpl": {Data: []byte("default")},
+	})
+
+	for lang, want := range map[string]string{"zh-TW": "zh", "zh": "zh", "fr-FR": "default"} {
+		tmpl, err := e.LoadHtml("hello", lang)
+		if err != nil {
+			t.Fatalf("LoadHtml(%s): %v", lang, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, nil); err != nil {
+			t.Fatalf("Execute(%s): %v", lang, err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("output mismatch for %s: got %q want %q", lang, got, want)
+		}
+	}
+
+	if _, err := e.LoadText("missing", "en"); err != ErrTemplateNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+ // TestEngine_TemplateFS_Frame verifies that frame validation and composition work against an fs.FS.
+func TestEngine_TemplateFS_Frame(t *testing.T) {
+	fsys := fstest.MapFS{
+		"default/page.tmpl": {Data: []byte("page->{{template \"_main.tmpl\"}}")},
+	}
+	e := New()
+	e.SetTemplateFS(fsys)
+
+	if _, err := e.LoadFrameHtml("page", "en"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, frame := range e.structTemplateFramesValue() {
+		fsys["default/"+frame+".tmpl"] = &fstest.MapFile{Data: []byte(frame)}
+	}
+	fsys["en/_main.tmpl"] = &fstest.MapFile{Data: []byte("en_main")}
+
+	tmpl, err := e.LoadFrameHtml("page", "en")
+	if err != nil {
+		t.Fatalf("LoadFrameHtml: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "page.tmpl", nil); err != nil {
+		t.Fatalf("ExecuteTemplate: %v", err)
+	}
+	if got, want := buf.String(), "page->en_main"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+ // TestTemplateFS_DefaultEngine verifies that assigning TemplateFS redirects the package-level
+ // loaders away from TemplateRootPath.
+func TestTemplateFS_DefaultEngine(t *testing.T) {
+	root := withTempTemplateRoot(t)
+	resetGlobals(t, root)
+
+	writeTemplateFile(t, root, "default", "hello", "disk")
+	TemplateFS = fstest.MapFS{"default/hello.tmpl": {Data: []byte("fs")}}
+
+	tmpl, err := LoadText("hello", "en")
+	if err != nil {
+		t.Fatalf("LoadText: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got, want := buf.String(), "fs"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+ // TestEngine_Debug_PerEngine verifies that debug mode is an Engine setting: a debug engine reparses
+ // changed files while a production engine over the same root keeps serving its cached template.
+func TestEngine_Debug_PerEngine(t *testing.T) {
+	root := withTempTemplateRoot(t)
+	path := writeTemplateFile(t, root, "default", "hello", "v1")
+
+	production, preview := New(), New()
+	production.SetTemplateRootPath(root)
+	preview.SetTemplateRootPath(root)
+	preview.SetDebug(true)
+
+	for _, e := range []*Engine{production, preview} {
+		if _, err := e.LoadHtml("hello", "en"); err != nil {
+			t.Fatalf("LoadHtml(v1): %v", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte("v2"), 0o644); err != nil {
+		t.Fatalf("rewrite template: %v", err)
+	}
+
+	for e, want := range map[*Engine]string{production: "v1", preview: "v2"} {
+		var buf bytes.Buffer
+		if err := e.RenderHtml(&buf, "hello", "en", nil); err != nil {
+			t.Fatalf("RenderHtml: %v", err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("output mismatch: got %q want %q", got, want)
+		}
+	}
+
+	preview.SetDebug(false)
+	if preview.IsDebug() {
+		t.Fatalf("expected debug to be switched off")
+	}
+}
+
+ // TestEngine_Debug_Env verifies that the debug environment variables only drive Default().
+func TestEngine_Debug_Env(t *testing.T) {
+	t.Setenv("KKAPP_DEBUG", "TRUE")
+
+	if !Default().IsDebug() {
+		t.Fatalf("expected Default() to follow KKAPP_DEBUG")
+	}
+	if New().IsDebug() {
+		t.Fatalf("expected New() to ignore KKAPP_DEBUG")
+	}
+}
+
+ // TestLoadFrameHtml_PerLangValidation verifies that a language providing a frame missing from default
+ // passes validation, that other languages report exactly the missing frame, and that a frame removed
+ // later is noticed once it is invalidated.
+func TestLoadFrameHtml_PerLangValidation(t *testing.T) {
+	files := map[string]string{"default/page.tmpl": "page", "en/_header_content.tmpl": "en header"}
+	for _, frame := range StructTemplateFrames {
+		if frame != "_header_content" {
+			files["default/"+frame+".tmpl"] = frame
+		}
+	}
+	e, fsys := newMapFSEngine(files)
+
+	if _, err := e.LoadFrameHtml("page", "en-US"); err != nil {
+		t.Fatalf("LoadFrameHtml(en-US): %v", err)
+	}
+	_, err := e.LoadFrameHtml("page", "ja")
+	var missing *MissingFrameError
+	if !errors.As(err, &missing) || missing.Frame != "_header_content" || missing.Lang != "ja" {
+		t.Fatalf("LoadFrameHtml(ja): expected _header_content to be missing for ja, got %v", err)
+	}
+
+	delete(fsys, "en/_header_content.tmpl")
+	e.Invalidate("_header_content", "")
+	if _, err := e.LoadFrameHtml("page", "en-US"); !errors.As(err, &missing) || missing.Lang != "en-US" {
+		t.Fatalf("LoadFrameHtml(en-US) after removal: expected a missing frame, got %v", err)
+	}
+}
+
+ // TestLoadFrameHtml_StableNames verifies that templates are named after their logical names whatever
+ // language directory they resolve to, that Execute runs the page of a frame template, and that the
+ // file base names remain usable as aliases.
+func TestLoadFrameHtml_StableNames(t *testing.T) {
+	files := map[string]string{
+		"default/about/team.tmpl": "team->{{template \"_main\" .}}",
+		"ja/about/team.tmpl":      "ja team->{{template \"_main.tmpl\" .}}",
+	}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = frame
+	}
+	e, _ := newMapFSEngine(files)
+
+	for _, lang := range []string{"en", "ja"} {
+		htmlTmpl, err := e.LoadHtml("about/team", lang)
+		if err != nil {
+			t.Fatalf("LoadHtml(%s): %v", lang, err)
+		}
+		textTmpl, err := e.LoadText("about/team", lang)
+		if err != nil {
+			t.Fatalf("LoadText(%s): %v", lang, err)
+		}
+		frameTmpl, err := e.LoadFrameHtml("about/team", lang)
+		if err != nil {
+			t.Fatalf("LoadFrameHtml(%s): %v", lang, err)
+		}
+		for _, got := range []string{htmlTmpl.Name(), textTmpl.Name(), frameTmpl.Name()} {
+			if got != "about/team" {
+				t.Fatalf("%s: template named %q, want %q", lang, got, "about/team")
+			}
+		}
+		for _, name := range []string{"_main", "_main.tmpl", "team.tmpl"} {
+			if frameTmpl.Lookup(name) == nil {
+				t.Fatalf("%s: expected template %q to be defined", lang, name)
+			}
+		}
+	}
+
+	tmpl, _ := e.LoadFrameHtml("about/team", "ja")
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got, want := buf.String(), "ja team->_main"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
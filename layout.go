@@ -0,0 +1,128 @@
+package kktemplate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrLayoutCycle = fmt.Errorf("layout extends itself")
+
+// MissingLayoutError reports a layout that does not resolve for Lang. It
+// matches ErrTemplateNotFound with errors.Is.
+type MissingLayoutError struct {
+	Layout string
+	// ExtendedBy is the template extending Layout.
+	ExtendedBy string
+	Lang       string
+}
+
+func (e *MissingLayoutError) Error() string {
+	return fmt.Sprintf("layout %q extended by %q: %v", e.Layout, e.ExtendedBy, ErrTemplateNotFound)
+}
+
+func (e *MissingLayoutError) Unwrap() error {
+	return ErrTemplateNotFound
+}
+
+// extendsDirective matches a leading {{/* extends "name" */}} comment, which
+// makes the template extend the layout template name.
+var extendsDirective = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*extends\s+"([^"]+)"\s*\*/\s*-?\}\}`)
+
+// layoutFile is one template of a layout chain.
+type layoutFile struct {
+	name string
+	path string
+	data []byte
+}
+
+// extendsOf returns the layout name data extends, or "".
+func extendsOf(data []byte) string {
+	if match := extendsDirective.FindSubmatch(data); match != nil {
+		return strings.TrimSuffix(string(match[1]), ".tmpl")
+	}
+	return ""
+}
+
+// layoutChain reads name for lang and every layout it extends, page first.
+// Layouts are resolved like pages, through the language chain of lang.
+func (e *Engine) layoutChain(name string, lang string) ([]layoutFile, error) {
+	var chain []layoutFile
+	seen := map[string]bool{}
+	for current := name; current != ""; {
+		if seen[current] {
+			return nil, fmt.Errorf("%w: %s", ErrLayoutCycle, layoutChainString(chain, current))
+		}
+		seen[current] = true
+
+		tmplPath := e.getRealTemplatePath(current, lang)
+		data := e.readTemplate(tmplPath)
+		if data == nil {
+			if len(chain) == 0 {
+				return nil, ErrTemplateNotFound
+			}
+			return nil, &MissingLayoutError{Layout: current, ExtendedBy: chain[len(chain)-1].name, Lang: lang}
+		}
+		chain = append(chain, layoutFile{name: current, path: tmplPath, data: data})
+		current = extendsOf(data)
+	}
+	return chain, nil
+}
+
+func layoutChainString(chain []layoutFile, last string) string {
+	names := make([]string, 0, len(chain)+1)
+	for _, file := range chain {
+		names = append(names, file.name)
+	}
+	return strings.Join(append(names, last), " -> ")
+}
+
+func layoutChainNames(chain []layoutFile) []string {
+	names := make([]string, 0, len(chain))
+	for _, file := range chain {
+		names = append(names, file.name)
+	}
+	return names
+}
+
+type chainTemplate[T any] interface {
+	Name() string
+	New(name string) T
+	Parse(text string) (T, error)
+}
+
+// parseLayoutChain parses chain into t, from the outermost layout down to the
+// page, so that each file's defines override the blocks of the layouts above
+// it. The outermost layout is parsed as entry, the template that executes,
+// and under its own name; the other layouts are named after their logical
+// names. The page itself, when it extends a layout, only contributes defines
+// and is named after its path.
+func parseLayoutChain[T chainTemplate[T]](t T, entry string, chain []layoutFile) error {
+	parse := func(name string, data []byte) error {
+		tmpl := t
+		if name != t.Name() {
+			tmpl = t.New(name)
+		}
+		_, err := tmpl.Parse(string(data))
+		return err
+	}
+
+	root := chain[len(chain)-1]
+	if len(chain) > 1 {
+		if err := parse(root.name, root.data); err != nil {
+			return err
+		}
+	}
+	if err := parse(entry, root.data); err != nil {
+		return err
+	}
+	for i := len(chain) - 2; i > 0; i-- {
+		if err := parse(chain[i].name, chain[i].data); err != nil {
+			return err
+		}
+	}
+	if len(chain) > 1 {
+		return parse(chain[0].path, chain[0].data)
+	}
+	return nil
+}
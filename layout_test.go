@@ -0,0 +1,131 @@
+// layout_test.go contains unit tests for layout inheritance through the
+// extends directive.
+//
+// Test Case Index:
+// - TestLayout_NestedBlocks: blocks are overridden at every level of a page -> admin -> base chain.
+// - TestLayout_Text: text templates extend layouts the same way.
+// - TestLayout_Frame: frame pages extend layouts and keep the struct template frames.
+// - TestLayout_LangFallback: layouts resolve through the language chain of the page.
+// - TestLayout_Errors: missing layouts fail with a MissingLayoutError and cycles with ErrLayoutCycle.
+// - TestLayout_InvalidateLayout: invalidating a layout drops the pages extending it.
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var layoutFiles = map[string]string{
+	"default/base.tmpl":  "<html>{{block \"title\" .}}Site{{end}}|{{block \"content\" .}}empty{{end}}</html>",
+	"default/admin.tmpl": "{{/* extends \"base\" */}}{{define \"title\"}}Admin{{end}}{{define \"content\"}}<nav/>{{block \"body\" .}}{{end}}{{end}}",
+	"default/users.tmpl": "{{- /* extends \"admin\" */ -}}\n{{define \"body\"}}users:{{.}}{{end}}",
+	"default/about.tmpl": "{{/* extends \"base\" */}}{{define \"content\"}}about{{end}}",
+}
+
+func TestLayout_NestedBlocks(t *testing.T) {
+	e, _ := newMapFSEngine(layoutFiles)
+
+	cases := map[string]string{
+		"users": "<html>Admin|<nav/>users:x</html>",
+		"about": "<html>Site|about</html>",
+		"base":  "<html>Site|empty</html>",
+	}
+	for name, want := range cases {
+		var buf bytes.Buffer
+		if err := e.RenderHtml(&buf, name, "en", "x"); err != nil {
+			t.Fatalf("RenderHtml(%s): %v", name, err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("RenderHtml(%s): got %q want %q", name, got, want)
+		}
+	}
+}
+
+func TestLayout_Text(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/mail.tmpl":    "Hello,\n{{block \"body\" .}}{{end}}\n-- {{block \"sign\" .}}team{{end}}",
+		"default/welcome.tmpl": "{{/* extends \"mail\" */}}{{define \"body\"}}welcome {{.}}{{end}}",
+	})
+
+	var buf bytes.Buffer
+	if err := e.RenderText(&buf, "welcome", "en", "<ann>"); err != nil {
+		t.Fatalf("RenderText: %v", err)
+	}
+	if got, want := buf.String(), "Hello,\nwelcome <ann>\n-- team"; got != want {
+		t.Fatalf("RenderText: got %q want %q", got, want)
+	}
+}
+
+func TestLayout_Frame(t *testing.T) {
+	files := map[string]string{
+		"default/base.tmpl": "{{template \"_main.tmpl\" .}}[{{block \"content\" .}}{{end}}]",
+		"default/page.tmpl": "{{/* extends \"base\" */}}{{define \"content\"}}page:{{.}}{{end}}",
+	}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = frame
+	}
+	e, _ := newMapFSEngine(files)
+
+	var buf bytes.Buffer
+	if err := e.RenderFrame(&buf, "page", "en", "x"); err != nil {
+		t.Fatalf("RenderFrame: %v", err)
+	}
+	if got, want := buf.String(), "_main[page:x]"; got != want {
+		t.Fatalf("RenderFrame: got %q want %q", got, want)
+	}
+}
+
+func TestLayout_LangFallback(t *testing.T) {
+	files := map[string]string{"en/base.tmpl": "<en>{{block \"content\" .}}{{end}}</en>"}
+	for name, content := range layoutFiles {
+		files[name] = content
+	}
+	e, _ := newMapFSEngine(files)
+
+	var buf bytes.Buffer
+	if err := e.RenderHtml(&buf, "about", "en-US", nil); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if got, want := buf.String(), "<en>about</en>"; got != want {
+		t.Fatalf("RenderHtml: got %q want %q", got, want)
+	}
+}
+
+func TestLayout_Errors(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/orphan.tmpl": "{{/* extends \"missing\" */}}",
+		"default/loop.tmpl":   "{{/* extends \"loop\" */}}",
+	})
+
+	_, err := e.LoadHtml("orphan", "en")
+	var missing *MissingLayoutError
+	if !errors.Is(err, ErrTemplateNotFound) || !errors.As(err, &missing) {
+		t.Fatalf("orphan: expected a *MissingLayoutError, got %v", err)
+	}
+	if missing.Layout != "missing" || missing.ExtendedBy != "orphan" || missing.Lang != "en" {
+		t.Fatalf("orphan: unexpected error fields %+v", missing)
+	}
+	if _, err := e.LoadText("loop", "en"); !errors.Is(err, ErrLayoutCycle) {
+		t.Fatalf("loop: expected ErrLayoutCycle, got %v", err)
+	}
+}
+
+func TestLayout_InvalidateLayout(t *testing.T) {
+	e, _ := newMapFSEngine(layoutFiles)
+	for _, name := range []string{"users", "about", "base"} {
+		if _, err := e.LoadHtml(name, "en"); err != nil {
+			t.Fatalf("LoadHtml(%s): %v", name, err)
+		}
+	}
+
+	e.Invalidate("admin", "")
+	if got, want := e.CachedKeys(), []string{"html:about-en", "html:base-en"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after Invalidate(admin): got %v want %v", got, want)
+	}
+	e.Invalidate("base", "")
+	if got := e.CachedKeys(); len(got) != 0 {
+		t.Fatalf("after Invalidate(base): got %v", got)
+	}
+}
@@ -0,0 +1,369 @@
+package kktemplate
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is one finding of Engine.Lint.
+type LintIssue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Lang     string `json:"lang,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location += ":" + strconv.Itoa(i.Line)
+	}
+	return fmt.Sprintf("%s: %s: [%s] %s", location, i.Severity, i.Rule, i.Message)
+}
+
+// builtinTemplateFuncs are the functions text/template and html/template
+// predefine.
+var builtinTemplateFuncs = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or", "print", "printf", "println", "urlquery",
+	"eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// Lint checks the template tree the way the engine loads it and reports
+// parse errors, calls to functions that are neither built in nor in the
+// engine's FuncMap (extraFuncs adds names registered elsewhere), template
+// calls to undefined names, layouts that are missing or extend themselves,
+// frame files missing from or not listed in the struct template frames or a
+// frame set, and templates of default that a language does not provide.
+// Issues are sorted by file and line.
+func (e *Engine) Lint(extraFuncs ...string) ([]LintIssue, error) {
+	if e == nil {
+		return nil, ErrInvalidEngine
+	}
+
+	dirs, _, err := e.templateTree()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, name := range builtinTemplateFuncs {
+		known[name] = true
+	}
+	for name := range e.generateHTMLFuncMap("default") {
+		known[name] = true
+	}
+	for _, name := range extraFuncs {
+		known[name] = true
+	}
+
+	frames := map[string]bool{}
+	for _, frame := range e.allFrames() {
+		frames[frame] = true
+	}
+
+	fsys := e.sourceFS()
+	var issues []LintIssue
+	for _, frame := range e.structTemplateFramesValue() {
+		if e.getRealTemplatePath(frame, "default") == "" {
+			issues = append(issues, LintIssue{
+				File: path.Join("default", frame+".tmpl"), Lang: "default", Severity: LintError, Rule: "frame",
+				Message: fmt.Sprintf("frame %q is listed in the struct template frames but has no template", frame),
+			})
+		}
+	}
+	for _, set := range e.FrameSets() {
+		setFrames, _ := e.frameSet(set)
+		for _, frame := range setFrames {
+			if e.getRealTemplatePath(frame, "default") == "" {
+				issues = append(issues, LintIssue{
+					File: path.Join("default", frame+".tmpl"), Lang: "default", Severity: LintError, Rule: "frame",
+					Message: fmt.Sprintf("frame %q of frame set %q has no template", frame, set),
+				})
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		files := e.langTemplates(fsys, dir)
+		frameDefines := e.lintFrameDefines(dir)
+		for defineName := range e.lintPartialDefines(dir) {
+			frameDefines[defineName] = true
+		}
+		for _, name := range sortedKeys(files) {
+			file := files[name]
+			if strings.HasPrefix(name, "_") && !strings.Contains(name, "/") && !frames[name] {
+				issues = append(issues, LintIssue{
+					File: file, Lang: dir, Severity: LintWarning, Rule: "frame",
+					Message: fmt.Sprintf("frame-like file %q is not listed in the struct template frames", name),
+				})
+			}
+
+			data, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				issues = append(issues, LintIssue{File: file, Lang: dir, Severity: LintError, Rule: "read", Message: err.Error()})
+				continue
+			}
+			trees, err := parseLintTrees(file, string(data))
+			if err != nil {
+				line, msg := splitParseError(err)
+				issues = append(issues, LintIssue{File: file, Line: line, Lang: dir, Severity: LintError, Rule: "parse", Message: msg})
+				continue
+			}
+
+			defined := map[string]bool{}
+			for defineName := range trees {
+				defined[defineName] = true
+			}
+			for defineName := range frameDefines {
+				defined[defineName] = true
+			}
+			layoutDefines, err := e.lintLayoutDefines(name, dir)
+			if err != nil {
+				issues = append(issues, LintIssue{File: file, Lang: dir, Severity: LintError, Rule: "extends", Message: err.Error()})
+			}
+			for defineName := range layoutDefines {
+				defined[defineName] = true
+			}
+			for _, tree := range trees {
+				issues = append(issues, lintTree(tree, file, dir, known, defined)...)
+			}
+		}
+
+		if dir == "default" {
+			continue
+		}
+		for _, name := range sortedKeys(e.langTemplates(fsys, "default")) {
+			if _, ok := files[name]; ok || strings.HasPrefix(path.Base(name), "_") || e.isPartial(name) || e.resolvesOutsideDefault(name, dir) {
+				continue
+			}
+			issues = append(issues, LintIssue{
+				File: path.Join(dir, name+".tmpl"), Lang: dir, Severity: LintWarning, Rule: "missing-lang",
+				Message: fmt.Sprintf("template %q exists in default but not for %s", name, dir),
+			})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues, nil
+}
+
+// langTemplates maps the logical names of the templates under dir to their
+// paths.
+func (e *Engine) langTemplates(fsys fs.FS, dir string) map[string]string {
+	files := map[string]string{}
+	_ = fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(p, ".tmpl") {
+			files[strings.TrimSuffix(strings.TrimPrefix(p, dir+"/"), ".tmpl")] = p
+		}
+		return nil
+	})
+	return files
+}
+
+func (e *Engine) resolvesOutsideDefault(name string, lang string) bool {
+	fsys := e.sourceFS()
+	for _, candidate := range e.LangChain(lang) {
+		if candidate != "default" && e.templateExists(fsys, path.Join(candidate, name+".tmpl")) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintFrameDefines returns the template names the frame files, of the struct
+// template frames and of every frame set, provide to a page of lang.
+func (e *Engine) lintFrameDefines(lang string) map[string]bool {
+	defined := map[string]bool{}
+	for _, frame := range e.allFrames() {
+		framePath := e.getRealTemplatePath(frame, lang)
+		if framePath == "" {
+			continue
+		}
+		defined[frame] = true
+		defined[path.Base(framePath)] = true
+		data := e.readTemplate(framePath)
+		trees, err := parseLintTrees(framePath, string(data))
+		if err != nil {
+			continue
+		}
+		for name := range trees {
+			if name != framePath {
+				defined[name] = true
+			}
+		}
+	}
+	return defined
+}
+
+// lintPartialDefines returns the template names the partials provide to every
+// template of lang.
+func (e *Engine) lintPartialDefines(lang string) map[string]bool {
+	defined := map[string]bool{}
+	for _, partial := range e.partialFiles(lang) {
+		defined[partial.name] = true
+		trees, err := parseLintTrees(partial.path, string(partial.data))
+		if err != nil {
+			continue
+		}
+		for defineName := range trees {
+			if defineName != partial.path {
+				defined[defineName] = true
+			}
+		}
+	}
+	return defined
+}
+
+// lintLayoutDefines returns the template names the layouts name extends
+// provide to it.
+func (e *Engine) lintLayoutDefines(name string, lang string) (map[string]bool, error) {
+	chain, err := e.layoutChain(name, lang)
+	if err != nil {
+		return nil, err
+	}
+	defined := map[string]bool{}
+	for _, layout := range chain[1:] {
+		defined[layout.name] = true
+		trees, err := parseLintTrees(layout.path, string(layout.data))
+		if err != nil {
+			continue
+		}
+		for defineName := range trees {
+			if defineName != layout.path {
+				defined[defineName] = true
+			}
+		}
+	}
+	return defined, nil
+}
+
+func parseLintTrees(name string, text string) (map[string]*parse.Tree, error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	trees := map[string]*parse.Tree{}
+	if _, err := tree.Parse(text, "", "", trees); err != nil {
+		return nil, err
+	}
+	return trees, nil
+}
+
+// splitParseError extracts the line from "template: <name>:<line>: <msg>".
+func splitParseError(err error) (int, string) {
+	match := templateErrorLocation.FindStringSubmatchIndex(err.Error())
+	if match == nil {
+		return 0, err.Error()
+	}
+	msg := err.Error()
+	line, _ := strconv.Atoi(msg[match[4]:match[5]])
+	return line, strings.TrimSpace(msg[match[1]:])
+}
+
+func lintTree(tree *parse.Tree, file string, lang string, known map[string]bool, defined map[string]bool) []LintIssue {
+	var issues []LintIssue
+	walkTemplateNodes(tree.Root, func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.IdentifierNode:
+			if !known[n.Ident] {
+				issues = append(issues, LintIssue{
+					File: file, Line: nodeLine(tree, n), Lang: lang, Severity: LintError, Rule: "undefined-func",
+					Message: fmt.Sprintf("function %q is not defined", n.Ident),
+				})
+			}
+		case *parse.TemplateNode:
+			if !defined[n.Name] {
+				issues = append(issues, LintIssue{
+					File: file, Line: nodeLine(tree, n), Lang: lang, Severity: LintError, Rule: "undefined-template",
+					Message: fmt.Sprintf("template %q is not defined", n.Name),
+				})
+			}
+		}
+	})
+	return issues
+}
+
+func nodeLine(tree *parse.Tree, node parse.Node) int {
+	location, _ := tree.ErrorContext(node)
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	return line
+}
+
+// walkTemplateNodes calls fn for every node below node, depth first.
+func walkTemplateNodes(node parse.Node, fn func(parse.Node)) {
+	if node == nil {
+		return
+	}
+	fn(node)
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkTemplateNodes(child, fn)
+		}
+	case *parse.ActionNode:
+		walkTemplateNodes(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, decl := range n.Decl {
+			walkTemplateNodes(decl, fn)
+		}
+		for _, cmd := range n.Cmds {
+			walkTemplateNodes(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkTemplateNodes(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkTemplateNodes(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkTemplateNodes(n.Pipe, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(parse.Node)) {
+	walkTemplateNodes(n.Pipe, fn)
+	walkTemplateNodes(n.List, fn)
+	if n.ElseList != nil {
+		walkTemplateNodes(n.ElseList, fn)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,127 @@
+// lint_test.go contains unit tests for Engine.Lint.
+//
+// Test Case Index:
+// - TestLint_Clean: a consistent template tree produces no issues.
+// - TestLint_Issues: parse errors, unknown functions, undefined templates, frame problems and missing languages are reported.
+// - TestLint_ExtraFuncs: names passed to Lint are accepted as registered functions.
+// - TestLint_Layouts: templates defined by extended layouts are known and broken extends chains are reported.
+// - TestLint_Partials: partial defines are known to every template and partials need no per-language copy.
+package kktemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func lintFrameFiles(files map[string]string) map[string]string {
+	for _, frame := range StructTemplateFrames {
+		if _, ok := files["default/"+frame+".tmpl"]; !ok {
+			files["default/"+frame+".tmpl"] = "{{define \"" + frame + "-block\"}}" + frame + "{{end}}"
+		}
+	}
+	return files
+}
+
+func TestLint_Clean(t *testing.T) {
+	e, _ := newMapFSEngine(lintFrameFiles(map[string]string{
+		"default/page.tmpl": "{{T \"title\"}}{{template \"_main.tmpl\" .}}{{template \"_main-block\"}}{{template \"local\"}}{{define \"local\"}}{{len .}}{{end}}",
+		"en/page.tmpl":      "{{printf \"%s\" .}}",
+	}))
+
+	issues, err := e.Lint()
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestLint_Issues(t *testing.T) {
+	files := lintFrameFiles(map[string]string{
+		"default/page.tmpl":   "ok\n{{unknownFunc .}}\n{{template \"nope\"}}",
+		"default/broken.tmpl": "line1\nline2\n{{if}}",
+		"default/_extra.tmpl": "extra",
+		"ja/broken.tmpl":      "{{.}}",
+	})
+	delete(files, "default/_footer_claim.tmpl")
+	e, _ := newMapFSEngine(files)
+
+	issues, err := e.Lint()
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"default/_extra.tmpl: warning: [frame] frame-like file \"_extra\" is not listed in the struct template frames",
+		"default/_footer_claim.tmpl: error: [frame] frame \"_footer_claim\" is listed in the struct template frames but has no template",
+		"default/broken.tmpl:3: error: [parse] missing value for if",
+		"default/page.tmpl:2: error: [undefined-func] function \"unknownFunc\" is not defined",
+		"default/page.tmpl:3: error: [undefined-template] template \"nope\" is not defined",
+		"ja/page.tmpl: warning: [missing-lang] template \"page\" exists in default but not for ja",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("issues mismatch:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestLint_ExtraFuncs(t *testing.T) {
+	e, _ := newMapFSEngine(lintFrameFiles(map[string]string{"default/page.tmpl": "{{asset \"a.css\"}}"}))
+
+	issues, err := e.Lint("asset")
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestLint_Layouts(t *testing.T) {
+	e, _ := newMapFSEngine(lintFrameFiles(map[string]string{
+		"default/base.tmpl":    "{{block \"content\" .}}{{end}}{{define \"nav\"}}nav{{end}}",
+		"default/page.tmpl":    "{{/* extends \"base\" */}}{{define \"content\"}}{{template \"nav\"}}{{end}}",
+		"default/orphan.tmpl":  "{{/* extends \"missing\" */}}",
+		"default/cycle_a.tmpl": "{{/* extends \"cycle_b\" */}}",
+		"default/cycle_b.tmpl": "{{/* extends \"cycle_a\" */}}",
+	}))
+
+	issues, err := e.Lint()
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"default/cycle_a.tmpl: error: [extends] layout extends itself: cycle_a -> cycle_b -> cycle_a",
+		"default/cycle_b.tmpl: error: [extends] layout extends itself: cycle_b -> cycle_a -> cycle_b",
+		"default/orphan.tmpl: error: [extends] layout \"missing\" extended by \"orphan\": template file not found",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("issues mismatch:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestLint_Partials(t *testing.T) {
+	e, _ := newMapFSEngine(lintFrameFiles(map[string]string{
+		"default/partials/button.tmpl": "{{define \"button\"}}{{.}}{{end}}",
+		"default/page.tmpl":            "{{template \"button\" .}}",
+		"ja/page.tmpl":                 "{{template \"button\" .}}",
+	}))
+	e.SetPartialsDir("partials")
+
+	issues, err := e.Lint()
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
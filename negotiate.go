@@ -0,0 +1,195 @@
+package kktemplate
+
+import (
+	html "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	text "text/template"
+
+	"github.com/yetiz-org/goth-kktranslation"
+)
+
+// ParseAcceptLanguage returns the language tags of one or more
+// Accept-Language values ordered by descending q-value. Each value may be a
+// whole header ("zh-TW,zh;q=0.9,en;q=0.8") or a single preference
+// ("en;q=0.8"). Tags with q=0, the "*" wildcard and malformed entries are
+// dropped; equal q-values keep their original order.
+func ParseAcceptLanguage(values ...string) []string {
+	type preference struct {
+		tag string
+		q   float64
+	}
+
+	var prefs []preference
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			fields := strings.Split(part, ";")
+			tag := strings.TrimSpace(fields[0])
+			if tag == "" || tag == "*" {
+				continue
+			}
+
+			q := 1.0
+			for _, param := range fields[1:] {
+				param = strings.TrimSpace(param)
+				if len(param) < 2 || !strings.EqualFold(param[:2], "q=") {
+					continue
+				}
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil || v < 0 || v > 1 {
+					q = 0
+				} else {
+					q = v
+				}
+			}
+			if q > 0 {
+				prefs = append(prefs, preference{tag: tag, q: q})
+			}
+		}
+	}
+
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	tags := make([]string, 0, len(prefs))
+	for _, pref := range prefs {
+		tags = append(tags, pref.tag)
+	}
+	return tags
+}
+
+func NegotiateLang(name string, acceptLanguage ...string) (dir string, lang string) {
+	return defaultEngine.NegotiateLang(name, acceptLanguage...)
+}
+
+// NegotiateLang walks the Accept-Language preferences and returns the
+// language directory in which name was found for the first of them, ignoring
+// the default directory, together with the language that preference stands
+// for; tags and directories are compared case-insensitively. A preference
+// resolved through its fallback chain, such as zh-TW through zh, returns that
+// directory. When none matches, dir is "default".
+//
+// lang is the first entry of the preference's chain that is a known language
+// (a language directory, a translation file or a locale of the Format*
+// functions) spelled the way it is known, so de-DE on a page found only in
+// default is "de", and is "default" when no preference is known. dir is meant
+// for the Content-Language header and lang for the loaders, which resolve
+// name to dir and bind T and the Format* functions to lang.
+func (e *Engine) NegotiateLang(name string, acceptLanguage ...string) (dir string, lang string) {
+	fsys := e.sourceFS()
+	var dirs []string
+	fallback := ""
+	for _, pref := range ParseAcceptLanguage(acceptLanguage...) {
+		if dirs == nil {
+			dirs = langDirs(fsys)
+		}
+		known := ""
+		for _, candidate := range e.LangChain(pref) {
+			if candidate == "" || strings.EqualFold(candidate, "default") {
+				continue
+			}
+			if known == "" {
+				known = e.knownLang(dirs, candidate)
+			}
+			found := langDir(dirs, candidate)
+			if found == "" || !e.templateExists(fsys, path.Join(found, name+".tmpl")) {
+				continue
+			}
+			if e.getRealTemplatePath(name, known) != path.Join(found, name+".tmpl") {
+				known = found
+			}
+			return found, known
+		}
+		if fallback == "" {
+			fallback = known
+		}
+	}
+	if fallback == "" || e.getRealTemplatePath(name, fallback) != path.Join("default", name+".tmpl") {
+		fallback = "default"
+	}
+	return "default", fallback
+}
+
+// knownLang returns the spelling of candidate among the language directories,
+// the translation files and the locales of the Format* functions, or "".
+func (e *Engine) knownLang(dirs []string, candidate string) string {
+	if dir := langDir(dirs, candidate); dir != "" {
+		return dir
+	}
+	for _, langFile := range kktranslation.LangFiles() {
+		if langFile.Lang != "" && strings.EqualFold(langFile.Lang, candidate) {
+			return langFile.Lang
+		}
+	}
+	if tag := strings.ToLower(candidate); localeFormats[tag] != nil {
+		return tag
+	}
+	return ""
+}
+
+// langDirs lists the top level directories of fsys.
+func langDirs(fsys fs.FS) []string {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return []string{}
+	}
+	dirs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs
+}
+
+// langDir returns the directory of dirs spelled like candidate, preferring an
+// exact match over a case-insensitive one, or "".
+func langDir(dirs []string, candidate string) string {
+	folded := ""
+	for _, dir := range dirs {
+		if dir == candidate {
+			return dir
+		}
+		if folded == "" && strings.EqualFold(dir, candidate) {
+			folded = dir
+		}
+	}
+	return folded
+}
+
+func LoadHtmlAccept(name string, acceptLanguage ...string) (*html.Template, string, error) {
+	return defaultEngine.LoadHtmlAccept(name, acceptLanguage...)
+}
+
+// LoadHtmlAccept is LoadHtml with the language negotiated from
+// Accept-Language; it also returns the language directory the template was
+// found in, suitable for the Content-Language response header.
+func (e *Engine) LoadHtmlAccept(name string, acceptLanguage ...string) (*html.Template, string, error) {
+	dir, lang := e.NegotiateLang(name, acceptLanguage...)
+	tmpl, err := e.LoadHtml(name, lang)
+	return tmpl, dir, err
+}
+
+func LoadTextAccept(name string, acceptLanguage ...string) (*text.Template, string, error) {
+	return defaultEngine.LoadTextAccept(name, acceptLanguage...)
+}
+
+// LoadTextAccept is LoadText with the language negotiated from Accept-Language.
+func (e *Engine) LoadTextAccept(name string, acceptLanguage ...string) (*text.Template, string, error) {
+	dir, lang := e.NegotiateLang(name, acceptLanguage...)
+	tmpl, err := e.LoadText(name, lang)
+	return tmpl, dir, err
+}
+
+func LoadFrameHtmlAccept(name string, acceptLanguage ...string) (*html.Template, string, error) {
+	return defaultEngine.LoadFrameHtmlAccept(name, acceptLanguage...)
+}
+
+// LoadFrameHtmlAccept is LoadFrameHtml with the language negotiated from
+// Accept-Language.
+func (e *Engine) LoadFrameHtmlAccept(name string, acceptLanguage ...string) (*html.Template, string, error) {
+	dir, lang := e.NegotiateLang(name, acceptLanguage...)
+	tmpl, err := e.LoadFrameHtml(name, lang)
+	return tmpl, dir, err
+}
@@ -0,0 +1,74 @@
+// negotiate_test.go contains unit tests for Accept-Language negotiation.
+//
+// Test Case Index:
+// - TestParseAcceptLanguage: header values and single preferences are ordered by q-value, dropping q=0 and "*".
+// - TestNegotiateLang: the preference list is walked against language directories that actually hold the template, returning the directory found and the known language matched.
+// - TestLoadHtmlAccept: LoadHtmlAccept returns the negotiated template together with its language.
+package kktemplate
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("fr;q=0.5, zh-TW ,*;q=0.1,de;q=0", "en;q=0.8", "ja;q=bad")
+	want := []string{"zh-TW", "en", "fr"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseAcceptLanguage: got %v want %v", got, want)
+	}
+
+	if got := ParseAcceptLanguage(""); len(got) != 0 {
+		t.Fatalf("expected no tags, got %v", got)
+	}
+}
+
+func TestNegotiateLang(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/hello.tmpl": "default",
+		"en/hello.tmpl":      "en",
+		"zh/hello.tmpl":      "zh",
+	})
+
+	cases := []struct {
+		accept string
+		dir    string
+		lang   string
+	}{
+		{"fr-FR,en;q=0.8", "en", "en"},
+		{"zh-TW,en;q=0.8", "zh", "zh-tw"},
+		{"en;q=0.2,zh-HK;q=0.9", "zh", "zh-hk"},
+		{"EN-us", "en", "en"},
+		{"fr-FR,de", "default", "fr"},
+		{"x-klingon", "default", "default"},
+		{"", "default", "default"},
+	}
+	for _, c := range cases {
+		if dir, lang := e.NegotiateLang("hello", c.accept); dir != c.dir || lang != c.lang {
+			t.Fatalf("NegotiateLang(%q): got %q %q want %q %q", c.accept, dir, lang, c.dir, c.lang)
+		}
+	}
+}
+
+func TestLoadHtmlAccept(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/hello.tmpl": "default",
+		"ja/hello.tmpl":      "ja",
+	})
+
+	tmpl, lang, err := e.LoadHtmlAccept("hello", "fr;q=0.9", "ja-JP;q=0.5")
+	if err != nil {
+		t.Fatalf("LoadHtmlAccept: %v", err)
+	}
+	if lang != "ja" {
+		t.Fatalf("resolved lang mismatch: got %q", lang)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got, want := buf.String(), "ja"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,243 @@
+package kktemplate
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// PageServerOptions configures Engine.PageServer.
+type PageServerOptions struct {
+	// FrameSet is the frame set pages are composed with; "" uses the struct
+	// template frames, or no frames when there are none.
+	FrameSet string
+
+	// Index is the page served for "/" and for paths ending in "/", relative
+	// to that directory. It defaults to "index".
+	Index string
+
+	// LangPrefix makes the first path segment the page language, as in
+	// /zh/about. A prefix spelling one of Langs in another case, or a region
+	// or script variant whose language chain reaches one of them, is
+	// permanently redirected to that language, e.g. /zh-TW/about to
+	// /zh/about. Other requests, including pages like /de-facto, are
+	// redirected to the language negotiated from Accept-Language.
+	LangPrefix bool
+
+	// Langs are the languages accepted as a prefix. They default to the
+	// language directories of the template root, except default, as found
+	// when PageServer is called.
+	Langs []string
+
+	// DefaultLang is the redirect target when negotiation matches none of
+	// Langs. It defaults to the first of Langs.
+	DefaultLang string
+
+	// Data, when not nil, builds the page data for each request.
+	Data DataFunc
+}
+
+func PageServer(opts PageServerOptions) http.Handler {
+	return defaultEngine.PageServer(opts)
+}
+
+// PageServer serves the template tree as pages: the URL path /about/team is
+// the template about/team, rendered like Render. Names with a segment
+// starting with "_" or ".", and partials, are never served.
+func (e *Engine) PageServer(opts PageServerOptions) http.Handler {
+	if opts.Index == "" {
+		opts.Index = "index"
+	}
+	langs := opts.Langs
+	if opts.LangPrefix && len(langs) == 0 {
+		langs = e.prefixLangs()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			e.errorPageValue()(w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		urlPath := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") && urlPath != "/" {
+			urlPath += "/"
+		}
+
+		if !opts.LangPrefix {
+			name := e.pageName(urlPath, opts.Index)
+			dir, lang := e.NegotiateLang(name, r.Header.Values("Accept-Language")...)
+			addVary(w.Header(), "Accept-Language")
+			e.servePage(w, r, opts, name, dir, lang)
+			return
+		}
+
+		segment, rest, _ := strings.Cut(strings.TrimPrefix(urlPath, "/"), "/")
+		if lang := e.prefixLang(segment, langs); lang != "" {
+			if segment != lang {
+				redirectPage(w, r, "/"+lang+strings.TrimPrefix(urlPath, "/"+segment), http.StatusMovedPermanently)
+				return
+			}
+			e.servePage(w, r, opts, e.pageName("/"+rest, opts.Index), lang, lang)
+			return
+		}
+
+		prefix := e.negotiatePrefix(r, langs, opts.DefaultLang)
+		if prefix == "" {
+			e.errorPageValue()(w, r, http.StatusNotFound, ErrTemplateNotFound)
+			return
+		}
+		addVary(w.Header(), "Accept-Language")
+		redirectPage(w, r, "/"+prefix+urlPath, http.StatusFound)
+	})
+}
+
+// redirectPage redirects r to target, keeping its query.
+func redirectPage(w http.ResponseWriter, r *http.Request, target string, code int) {
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, code)
+}
+
+func (e *Engine) servePage(w http.ResponseWriter, r *http.Request, opts PageServerOptions, name string, dir string, lang string) {
+	if !e.servable(name) {
+		e.errorPageValue()(w, r, http.StatusNotFound, ErrTemplateNotFound)
+		return
+	}
+
+	var data any
+	if opts.Data != nil {
+		var err error
+		if data, err = opts.Data(r); err != nil {
+			e.errorPageValue()(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	_ = e.renderPage(w, r, opts.FrameSet, name, dir, lang, data, nil)
+}
+
+// pageName maps a cleaned URL path to a template name.
+func (e *Engine) pageName(urlPath string, index string) string {
+	name := strings.TrimPrefix(urlPath, "/")
+	if name == "" || strings.HasSuffix(name, "/") {
+		name += index
+	}
+	return name
+}
+
+func (e *Engine) servable(name string) bool {
+	if e.isPartial(name) {
+		return false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || strings.HasPrefix(segment, "_") || strings.HasPrefix(segment, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// prefixLangs lists the language directories of the template root.
+func (e *Engine) prefixLangs() []string {
+	dirs, _, err := e.templateTree()
+	if err != nil {
+		return nil
+	}
+	langs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if dir != "default" {
+			langs = append(langs, dir)
+		}
+	}
+	return langs
+}
+
+// prefixLang returns the one of langs a path segment stands for: the one it
+// spells up to case, or, for a region or script variant such as zh-TW, the
+// first its language chain reaches. It returns "" when segment is not a
+// language, such as a page named de-facto.
+func (e *Engine) prefixLang(segment string, langs []string) string {
+	for _, lang := range langs {
+		if strings.EqualFold(segment, lang) {
+			return lang
+		}
+	}
+	if !langVariant(segment) {
+		return ""
+	}
+	for _, candidate := range e.LangChain(segment) {
+		for _, lang := range langs {
+			if strings.EqualFold(candidate, lang) {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// langVariant reports whether tag is a language subtag followed by a script
+// subtag, a region subtag or both, as in zh-Hant, zh-TW, zh-Hant-TW or
+// es-419.
+func langVariant(tag string) bool {
+	subtags := strings.Split(tag, "-")
+	if len(subtags) < 2 || len(subtags) > 3 || !asciiLetters(subtags[0], 2, 3) {
+		return false
+	}
+	rest := subtags[1:]
+	if asciiLetters(rest[0], 4, 4) {
+		rest = rest[1:]
+	}
+	switch {
+	case len(rest) == 0:
+		return true
+	case len(rest) > 1:
+		return false
+	}
+	region := rest[0]
+	if asciiLetters(region, 2, 2) {
+		return true
+	}
+	if len(region) != 3 {
+		return false
+	}
+	for i := 0; i < len(region); i++ {
+		if region[i] < '0' || region[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// asciiLetters reports whether s is minLen to maxLen ASCII letters.
+func asciiLetters(s string, minLen int, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// negotiatePrefix returns the first of langs in the language chain of the
+// request's preferences, or the fallback.
+func (e *Engine) negotiatePrefix(r *http.Request, langs []string, fallback string) string {
+	for _, pref := range ParseAcceptLanguage(r.Header.Values("Accept-Language")...) {
+		for _, candidate := range e.LangChain(pref) {
+			for _, lang := range langs {
+				if strings.EqualFold(candidate, lang) {
+					return lang
+				}
+			}
+		}
+	}
+	if fallback == "" && len(langs) > 0 {
+		fallback = langs[0]
+	}
+	return fallback
+}
@@ -0,0 +1,136 @@
+// pageserver_test.go contains unit tests for the directory-routed page server.
+//
+// Test Case Index:
+// - TestPageServer_Paths: URL paths map to template names through the frame set, with index pages and hidden names.
+// - TestPageServer_LangPrefix: language prefixes select the page language, other spellings and region variants redirect to it, and bare paths and page names starting with a language code redirect to the negotiated one.
+// - TestPageServer_LangsOnce: the default prefix languages are listed when the handler is built, not per request.
+// - TestPageServer_Method: only GET and HEAD are served.
+package kktemplate
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func serve(h http.Handler, target string, acceptLanguage string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if acceptLanguage != "" {
+		r.Header.Set("Accept-Language", acceptLanguage)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestPageServer_Paths(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/_site.tmpl":      "{{define \"_shell\"}}[{{.}}]{{end}}",
+		"default/index.tmpl":      "{{template \"_shell\" \"home\"}}",
+		"default/about/team.tmpl": "{{template \"_shell\" \"team\"}}",
+		"default/docs/index.tmpl": "{{template \"_shell\" \"docs\"}}",
+		"en/about/team.tmpl":      "{{template \"_shell\" \"en team\"}}",
+	})
+	e.RegisterFrameSet("site", []string{"_site"})
+	h := e.PageServer(PageServerOptions{FrameSet: "site"})
+
+	cases := []struct {
+		target string
+		accept string
+		status int
+		body   string
+	}{
+		{"/", "", http.StatusOK, "[home]"},
+		{"/about/team", "", http.StatusOK, "[team]"},
+		{"/about/team", "en-GB", http.StatusOK, "[en team]"},
+		{"/docs/", "", http.StatusOK, "[docs]"},
+		{"/_site", "", http.StatusNotFound, "Not Found\n"},
+		{"/missing", "", http.StatusNotFound, "Not Found\n"},
+	}
+	for _, c := range cases {
+		w := serve(h, c.target, c.accept)
+		if w.Code != c.status || w.Body.String() != c.body {
+			t.Fatalf("%s (%s): got %d %q want %d %q", c.target, c.accept, w.Code, w.Body.String(), c.status, c.body)
+		}
+	}
+}
+
+func TestPageServer_LangPrefix(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/about.tmpl": "about",
+		"zh/about.tmpl":      "關於",
+		"ja/about.tmpl":      "概要",
+		"ja/index.tmpl":      "ホーム",
+	})
+	e.SetStructTemplateFrames(nil)
+	h := e.PageServer(PageServerOptions{LangPrefix: true, DefaultLang: "zh"})
+
+	for target, want := range map[string]string{"/zh/about": "關於", "/ja/about": "概要", "/ja": "ホーム"} {
+		w := serve(h, target, "")
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Fatalf("%s: got %d %q want %q", target, w.Code, w.Body.String(), want)
+		}
+	}
+
+	canonical := map[string]string{"/zh-TW/about?x=1": "/zh/about?x=1", "/JA": "/ja", "/ja-JP/about": "/ja/about", "/zh-Hant-TW/about": "/zh/about", "/zh-419": "/zh"}
+	for target, want := range canonical {
+		w := serve(h, target, "")
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != want {
+			t.Fatalf("%s: got %d %q want redirect to %q", target, w.Code, w.Header().Get("Location"), want)
+		}
+	}
+	if got, want := fmt.Sprint(e.CachedKeys()), "[html:about-ja html:about-zh html:index-ja]"; got != want {
+		t.Fatalf("cached keys: got %s want %s", got, want)
+	}
+
+	for _, target := range []string{"/ja-facto", "/zh-x1/about", "/jazz"} {
+		w := serve(h, target, "ja")
+		if want := "/ja" + target; w.Code != http.StatusFound || w.Header().Get("Location") != want {
+			t.Fatalf("%s: got %d %q want redirect to %q", target, w.Code, w.Header().Get("Location"), want)
+		}
+	}
+
+	redirects := map[string]string{"ja-JP,en;q=0.5": "/ja/about?x=1", "fr": "/zh/about?x=1"}
+	for accept, want := range redirects {
+		w := serve(h, "/about?x=1", accept)
+		if w.Code != http.StatusFound || w.Header().Get("Location") != want {
+			t.Fatalf("redirect for %q: got %d %q want %q", accept, w.Code, w.Header().Get("Location"), want)
+		}
+		if w.Header().Get("Vary") != "Accept-Language" {
+			t.Fatalf("redirect for %q: missing Vary header", accept)
+		}
+	}
+
+	h = e.PageServer(PageServerOptions{LangPrefix: true, Langs: []string{"zh-TW", "ja"}})
+	w := serve(h, "/zh-TW/about", "")
+	if w.Code != http.StatusOK || w.Body.String() != "關於" || w.Header().Get("Content-Language") != "zh-TW" {
+		t.Fatalf("/zh-TW/about with explicit langs: got %d %q %q", w.Code, w.Body.String(), w.Header().Get("Content-Language"))
+	}
+}
+
+func TestPageServer_LangsOnce(t *testing.T) {
+	e, fsys := newMapFSEngine(map[string]string{"ja/index.tmpl": "ホーム"})
+	e.SetStructTemplateFrames(nil)
+	h := e.PageServer(PageServerOptions{LangPrefix: true})
+
+	fsys["ko/index.tmpl"] = &fstest.MapFile{Data: []byte("홈")}
+	if w := serve(h, "/", "ko"); w.Code != http.StatusFound || w.Header().Get("Location") != "/ja/" {
+		t.Fatalf("/: got %d %q, want redirect to /ja/", w.Code, w.Header().Get("Location"))
+	}
+	if w := serve(h, "/ja/", ""); w.Code != http.StatusOK || w.Body.String() != "ホーム" {
+		t.Fatalf("/ja/: got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestPageServer_Method(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/index.tmpl": "home"})
+	h := e.PageServer(PageServerOptions{})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD" {
+		t.Fatalf("POST: got %d Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+}
@@ -0,0 +1,84 @@
+package kktemplate
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SetPartialsDir makes every template the engine loads include the
+// templates under dir in the language directories, so their defines, e.g.
+// {{define "button"}}, can be called from any page. Each partial resolves
+// through the language chain like a page. An empty dir disables partials.
+// Cached templates are dropped.
+func (e *Engine) SetPartialsDir(dir string) {
+	if e == nil {
+		return
+	}
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if e.setPartialsDir != nil {
+		e.setPartialsDir(dir)
+	} else {
+		e.partialsDir = dir
+	}
+	e.InvalidateAll()
+}
+
+func (e *Engine) partialsDirValue() string {
+	if e == nil {
+		return ""
+	}
+	if e.getPartialsDir != nil {
+		return e.getPartialsDir()
+	}
+	return e.partialsDir
+}
+
+// isPartial reports whether the logical template name is a partial.
+func (e *Engine) isPartial(name string) bool {
+	dir := e.partialsDirValue()
+	return dir != "" && strings.HasPrefix(name, dir+"/")
+}
+
+// partialFiles resolves every partial for lang, sorted by logical name. A
+// partial exists when any language of the chain of lang provides it.
+func (e *Engine) partialFiles(lang string) []layoutFile {
+	dir := e.partialsDirValue()
+	if dir == "" {
+		return nil
+	}
+
+	fsys := e.sourceFS()
+	names := map[string]bool{}
+	for _, candidate := range e.LangChain(lang) {
+		_ = fs.WalkDir(fsys, path.Join(candidate, dir), func(p string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.HasSuffix(p, ".tmpl") {
+				names[strings.TrimSuffix(strings.TrimPrefix(p, candidate+"/"), ".tmpl")] = true
+			}
+			return nil
+		})
+	}
+
+	partials := make([]layoutFile, 0, len(names))
+	for _, name := range sortedKeys(names) {
+		tmplPath := e.getRealTemplatePath(name, lang)
+		if data := e.readTemplate(tmplPath); data != nil {
+			partials = append(partials, layoutFile{name: name, path: tmplPath, data: data})
+		}
+	}
+	return partials
+}
+
+// parsePartials parses partials into t under their logical names, skipping
+// skip, the template being loaded when it is a partial itself.
+func parsePartials[T chainTemplate[T]](t T, partials []layoutFile, skip string) error {
+	for _, partial := range partials {
+		if partial.name == skip {
+			continue
+		}
+		if _, err := t.New(partial.name).Parse(string(partial.data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
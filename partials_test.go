@@ -0,0 +1,119 @@
+// partials_test.go contains unit tests for the partials directory.
+//
+// Test Case Index:
+// - TestPartials_SharedDefines: partial defines are available to html, text and frame templates.
+// - TestPartials_LangFallback: each partial resolves through the language chain.
+// - TestPartials_Invalidate: a changed or added partial drops every cached template.
+// - TestPartials_Precompile: partials are not compiled as pages.
+package kktemplate
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func newPartialsEngine(files map[string]string) (*Engine, fstest.MapFS) {
+	e, fsys := newMapFSEngine(files)
+	e.SetPartialsDir("partials")
+	return e, fsys
+}
+
+func TestPartials_SharedDefines(t *testing.T) {
+	files := map[string]string{
+		"default/partials/button.tmpl": "{{define \"button\"}}<button>{{.}}</button>{{end}}",
+		"default/page.tmpl":            "{{template \"button\" \"ok\"}}",
+	}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = frame
+	}
+	e, _ := newPartialsEngine(files)
+
+	var buf bytes.Buffer
+	if err := e.RenderHtml(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if err := e.RenderText(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderText: %v", err)
+	}
+	if err := e.RenderFrame(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderFrame: %v", err)
+	}
+	if got, want := buf.String(), "<button>ok</button><button>ok</button><button>ok</button>"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestPartials_LangFallback(t *testing.T) {
+	e, _ := newPartialsEngine(map[string]string{
+		"default/partials/button.tmpl": "{{define \"button\"}}button{{end}}",
+		"ja/partials/button.tmpl":      "{{define \"button\"}}ボタン{{end}}",
+		"ja/partials/seal.tmpl":        "{{define \"seal\"}}印{{end}}",
+		"default/page.tmpl":            "{{template \"button\"}}",
+		"ja/page.tmpl":                 "{{template \"button\"}}{{template \"seal\"}}",
+	})
+
+	for lang, want := range map[string]string{"en-US": "button", "ja-JP": "ボタン印"} {
+		var buf bytes.Buffer
+		if err := e.RenderHtml(&buf, "page", lang, nil); err != nil {
+			t.Fatalf("RenderHtml(%s): %v", lang, err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("RenderHtml(%s): got %q want %q", lang, got, want)
+		}
+	}
+}
+
+func TestPartials_Invalidate(t *testing.T) {
+	e, fsys := newPartialsEngine(map[string]string{
+		"default/partials/button.tmpl": "{{define \"button\"}}v1{{end}}",
+		"default/page.tmpl":            "{{template \"button\"}}",
+		"default/other.tmpl":           "other",
+	})
+	for _, name := range []string{"page", "other"} {
+		if _, err := e.LoadHtml(name, "en"); err != nil {
+			t.Fatalf("LoadHtml(%s): %v", name, err)
+		}
+	}
+
+	fsys["default/partials/button.tmpl"] = &fstest.MapFile{Data: []byte("{{define \"button\"}}v2{{end}}{{define \"icon\"}}{{end}}")}
+	e.Invalidate("partials/button", "")
+	if got := e.CachedKeys(); len(got) != 0 {
+		t.Fatalf("after Invalidate: expected an empty cache, got %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := e.RenderHtml(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if got, want := buf.String(), "v2"; got != want {
+		t.Fatalf("RenderHtml: got %q want %q", got, want)
+	}
+
+	fsys["default/partials/badge.tmpl"] = &fstest.MapFile{Data: []byte("{{define \"badge\"}}new{{end}}")}
+	fsys["default/page.tmpl"] = &fstest.MapFile{Data: []byte("{{template \"badge\"}}")}
+	e.Invalidate("partials/badge", "")
+	buf.Reset()
+	if err := e.RenderHtml(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderHtml with new partial: %v", err)
+	}
+	if got, want := buf.String(), "new"; got != want {
+		t.Fatalf("RenderHtml with new partial: got %q want %q", got, want)
+	}
+}
+
+func TestPartials_Precompile(t *testing.T) {
+	e, _ := newPartialsEngine(map[string]string{
+		"default/partials/button.tmpl": "{{define \"button\"}}button{{end}}",
+		"default/page.tmpl":            "{{template \"button\"}}",
+	})
+	e.SetStructTemplateFrames(nil)
+
+	if err := e.Precompile(); err != nil {
+		t.Fatalf("Precompile: %v", err)
+	}
+	if got, want := e.CachedKeys(), []string{"html:page-default", "text:page-default"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CachedKeys: got %v want %v", got, want)
+	}
+}
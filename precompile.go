@@ -0,0 +1,185 @@
+package kktemplate
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TemplateFileError locates one template that failed to compile.
+type TemplateFileError struct {
+	File string
+	Line int
+	Lang string
+	Kind string
+	Err  error
+}
+
+func (e TemplateFileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: [%s %s] %v", e.File, e.Line, e.Kind, e.Lang, e.Err)
+	}
+	return fmt.Sprintf("%s: [%s %s] %v", e.File, e.Kind, e.Lang, e.Err)
+}
+
+// PrecompileError aggregates every failure found by Precompile.
+type PrecompileError struct {
+	Errors []TemplateFileError
+}
+
+func (e *PrecompileError) Error() string {
+	lines := make([]string, 0, len(e.Errors)+1)
+	lines = append(lines, fmt.Sprintf("kktemplate: %d template(s) failed to compile", len(e.Errors)))
+	for _, fileErr := range e.Errors {
+		lines = append(lines, "  "+fileErr.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *PrecompileError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, fileErr := range e.Errors {
+		errs = append(errs, fileErr.Err)
+	}
+	return errs
+}
+
+func Precompile(langs ...string) error {
+	return defaultEngine.Precompile(langs...)
+}
+
+// Precompile parses every template under the template root, except partials,
+// for each of langs (every language directory when langs is empty) as html,
+// text and, when the frame templates resolve for the language,
+// frame-composed html, filling the caches. It returns a *PrecompileError
+// listing every file and line that failed.
+func (e *Engine) Precompile(langs ...string) error {
+	if e == nil {
+		return ErrInvalidEngine
+	}
+
+	dirs, names, err := e.templateTree()
+	if err != nil {
+		return err
+	}
+	if len(langs) == 0 {
+		langs = dirs
+	}
+
+	frames := map[string]bool{}
+	for _, frame := range e.structTemplateFramesValue() {
+		frames[frame] = true
+	}
+
+	var errs []TemplateFileError
+	seen := map[string]bool{}
+	record := func(name string, lang string, kind string, err error) {
+		if err == nil {
+			return
+		}
+		fileErr := e.locateError(name, lang, kind, err)
+		key := fileErr.File + ":" + strconv.Itoa(fileErr.Line) + ":" + kind
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		errs = append(errs, fileErr)
+	}
+
+	for _, lang := range langs {
+		withFrames := len(frames) > 0 && e.frameExistValidate(lang) == nil
+		for _, name := range names {
+			if e.isPartial(name) || e.getRealTemplatePath(name, lang) == "" {
+				continue
+			}
+			_, err := e.LoadHtml(name, lang)
+			record(name, lang, cacheHTML, err)
+			_, err = e.LoadText(name, lang)
+			record(name, lang, cacheText, err)
+			if withFrames && !frames[name] {
+				_, err = e.LoadFrameHtml(name, lang)
+				record(name, lang, cacheFrame, err)
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].File != errs[j].File {
+			return errs[i].File < errs[j].File
+		}
+		return errs[i].Line < errs[j].Line
+	})
+	return &PrecompileError{Errors: errs}
+}
+
+// templateTree lists the language directories at the template root and the
+// logical names of every template inside them.
+func (e *Engine) templateTree() ([]string, []string, error) {
+	fsys := e.sourceFS()
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dirs []string
+	nameSet := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := entry.Name()
+		dirs = append(dirs, dir)
+		err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(p, ".tmpl") {
+				nameSet[strings.TrimSuffix(strings.TrimPrefix(p, dir+"/"), ".tmpl")] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	names := make([]string, 0, len(nameSet))
+	for name := range nameSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return dirs, names, nil
+}
+
+var templateErrorLocation = regexp.MustCompile(`template: ([^:]+):(\d+):`)
+
+// locateError maps a load error of name back to the file it came from,
+// using the template name and line reported by text/template. Templates are
+// named after their logical names, except pages extending a layout, which
+// are named after their paths.
+func (e *Engine) locateError(name string, lang string, kind string, err error) TemplateFileError {
+	fileErr := TemplateFileError{File: e.getRealTemplatePath(name, lang), Lang: lang, Kind: kind, Err: err}
+	match := templateErrorLocation.FindStringSubmatch(err.Error())
+	if match == nil {
+		return fileErr
+	}
+
+	fileErr.Line, _ = strconv.Atoi(match[2])
+	tmplName := strings.TrimSuffix(match[1], ".tmpl")
+	if tmplName == name || tmplName == path.Base(name) {
+		return fileErr
+	}
+	if e.templateExists(e.sourceFS(), match[1]) {
+		fileErr.File = match[1]
+	} else if tmplPath := e.getRealTemplatePath(tmplName, lang); tmplPath != "" {
+		fileErr.File = tmplPath
+	}
+	return fileErr
+}
@@ -0,0 +1,79 @@
+// precompile_test.go contains unit tests for eager template precompilation.
+//
+// Test Case Index:
+// - TestPrecompile_FillsCaches: Precompile parses every template of every language directory into the caches.
+// - TestPrecompile_Langs: Precompile limits itself to the requested languages.
+// - TestPrecompile_AggregatesErrors: every failing file is reported once per kind, with its line number.
+package kktemplate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPrecompile_FillsCaches(t *testing.T) {
+	files := map[string]string{
+		"default/hello.tmpl":      "hello",
+		"default/about/team.tmpl": "team",
+		"en/hello.tmpl":           "en hello",
+	}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = frame
+	}
+	e, _ := newMapFSEngine(files)
+
+	if err := e.Precompile(); err != nil {
+		t.Fatalf("Precompile: %v", err)
+	}
+
+	keys := map[string]bool{}
+	for _, key := range e.CachedKeys() {
+		keys[key] = true
+	}
+	for _, want := range []string{"html:hello-en", "text:hello-default", "frame:about/team-en", "html:_main-default"} {
+		if !keys[want] {
+			t.Fatalf("expected %s to be cached, got %v", want, e.CachedKeys())
+		}
+	}
+	if keys["frame:_main-en"] {
+		t.Fatalf("frame templates must not be composed with themselves")
+	}
+}
+
+func TestPrecompile_Langs(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/hello.tmpl": "hello", "en/hello.tmpl": "en"})
+
+	if err := e.Precompile("ja"); err != nil {
+		t.Fatalf("Precompile: %v", err)
+	}
+	if got, want := e.CachedKeys(), []string{"html:hello-ja", "text:hello-ja"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CachedKeys: got %v want %v", got, want)
+	}
+}
+
+func TestPrecompile_AggregatesErrors(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/ok.tmpl":     "ok",
+		"default/broken.tmpl": "line1\n{{if}}",
+		"en/bad.tmpl":         "{{end}}",
+	})
+
+	err := e.Precompile()
+	var precompileErr *PrecompileError
+	if !errors.As(err, &precompileErr) {
+		t.Fatalf("expected *PrecompileError, got %v", err)
+	}
+
+	var got []string
+	for _, fileErr := range precompileErr.Errors {
+		got = append(got, fileErr.File+":"+fileErr.Kind)
+		if fileErr.File == "default/broken.tmpl" && fileErr.Line != 2 {
+			t.Fatalf("expected line 2, got %d", fileErr.Line)
+		}
+	}
+	want := []string{"default/broken.tmpl:html", "default/broken.tmpl:text", "en/bad.tmpl:html", "en/bad.tmpl:text"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("errors: got %v want %v", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	html "html/template"
+	"io"
+	"sync"
+)
+
+var ErrTemplateParse = fmt.Errorf("template parse failed")
+var ErrTemplateExecute = fmt.Errorf("template execute failed")
+
+// RenderError is returned by the Render helpers. Kind is one of
+// ErrTemplateNotFound, ErrTemplateParse, ErrTemplateExecute or, for a nil or
+// unconfigured engine, ErrInvalidEngine, so callers can branch with
+// errors.Is; Err holds the underlying cause.
+type RenderError struct {
+	Kind error
+	Name string
+	Lang string
+	Err  error
+}
+
+func (e *RenderError) Error() string {
+	if e.Err == nil || e.Err == e.Kind {
+		return fmt.Sprintf("kktemplate: render %s (%s): %v", e.Name, e.Lang, e.Kind)
+	}
+	return fmt.Sprintf("kktemplate: render %s (%s): %v: %v", e.Name, e.Lang, e.Kind, e.Err)
+}
+
+func (e *RenderError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+func newLoadError(name string, lang string, err error) *RenderError {
+	kind := ErrTemplateParse
+	switch {
+	case errors.Is(err, ErrInvalidEngine):
+		kind = ErrInvalidEngine
+	case errors.Is(err, ErrTemplateNotFound):
+		kind = ErrTemplateNotFound
+	}
+	return &RenderError{Kind: kind, Name: name, Lang: lang, Err: err}
+}
+
+func newExecuteError(name string, lang string, err error) *RenderError {
+	return &RenderError{Kind: ErrTemplateExecute, Name: name, Lang: lang, Err: err}
+}
+
+func RenderHtml(w io.Writer, name string, lang string, data any, opts ...RenderOption) error {
+	return defaultEngine.RenderHtml(w, name, lang, data, opts...)
+}
+
+// RenderHtml loads the html template name for lang and executes it into w.
+// The output is buffered and written only when execution succeeds, unless
+// opts include Streaming; the same holds for every Render helper.
+func (e *Engine) RenderHtml(w io.Writer, name string, lang string, data any, opts ...RenderOption) error {
+	tmpl, copies, err := e.loadHtml(name, lang)
+	if err != nil {
+		return newLoadError(name, lang, err)
+	}
+	config := newRenderConfig(opts)
+	tmpl, release, err := withRequestFuncs(e, name, lang, tmpl, copies, config)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := executeBuffered(w, config, func(w io.Writer) error { return tmpl.Execute(w, data) }, config.htmlTransform()); err != nil {
+		return newExecuteError(name, lang, err)
+	}
+	return nil
+}
+
+func RenderText(w io.Writer, name string, lang string, data any, opts ...RenderOption) error {
+	return defaultEngine.RenderText(w, name, lang, data, opts...)
+}
+
+// RenderText loads the text template name for lang and executes it into w.
+func (e *Engine) RenderText(w io.Writer, name string, lang string, data any, opts ...RenderOption) error {
+	tmpl, copies, err := e.loadText(name, lang)
+	if err != nil {
+		return newLoadError(name, lang, err)
+	}
+	config := newRenderConfig(opts)
+	tmpl, release, err := withRequestFuncs(e, name, lang, tmpl, copies, config)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := executeBuffered(w, config, func(w io.Writer) error { return tmpl.Execute(w, data) }, nil); err != nil {
+		return newExecuteError(name, lang, err)
+	}
+	return nil
+}
+
+func RenderFrame(w io.Writer, name string, lang string, data any, opts ...RenderOption) error {
+	return defaultEngine.RenderFrame(w, name, lang, data, opts...)
+}
+
+// RenderFrame loads name composed with the frame templates and executes the
+// page itself as the entry point.
+func (e *Engine) RenderFrame(w io.Writer, name string, lang string, data any, opts ...RenderOption) error {
+	tmpl, copies, err := e.loadFrameHtml(name, lang)
+	if err != nil {
+		return newLoadError(name, lang, err)
+	}
+	return e.executeFrame(w, name, lang, tmpl, copies, data, opts)
+}
+
+func RenderFrameSet(w io.Writer, set string, name string, lang string, data any, opts ...RenderOption) error {
+	return defaultEngine.RenderFrameSet(w, set, name, lang, data, opts...)
+}
+
+// RenderFrameSet is RenderFrame with the frames of the frame set set.
+func (e *Engine) RenderFrameSet(w io.Writer, set string, name string, lang string, data any, opts ...RenderOption) error {
+	tmpl, copies, err := e.loadFrameSetHtml(set, name, lang)
+	if err != nil {
+		return newLoadError(name, lang, err)
+	}
+	return e.executeFrame(w, name, lang, tmpl, copies, data, opts)
+}
+
+func (e *Engine) executeFrame(w io.Writer, name string, lang string, tmpl *html.Template, copies *htmlCopies, data any, opts []RenderOption) error {
+	config := newRenderConfig(opts)
+	tmpl, release, err := withRequestFuncs(e, name, lang, tmpl, copies, config)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := executeBuffered(w, config, func(w io.Writer) error { return tmpl.Execute(w, data) }, config.htmlTransform()); err != nil {
+		return newExecuteError(name, lang, err)
+	}
+	return nil
+}
+
+// maxPooledBuffer bounds the buffers kept for reuse, so one very large page
+// does not pin its memory.
+const maxPooledBuffer = 1 << 20
+
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// executeBuffered runs execute into a pooled buffer and copies the output to
+// w only when it succeeds, so a failing template never leaves half a page in
+// w. transform, when not nil, rewrites the output first. With Streaming and no
+// transform, execute writes to w directly.
+func executeBuffered(w io.Writer, config *renderConfig, execute func(io.Writer) error, transform func([]byte) []byte) error {
+	if config.streaming && transform == nil {
+		return execute(w)
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if err := execute(buf); err != nil {
+		return err
+	}
+	if transform != nil {
+		_, err := w.Write(transform(buf.Bytes()))
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
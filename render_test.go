@@ -0,0 +1,137 @@
+// render_test.go contains unit tests for the Render helpers.
+//
+// Test Case Index:
+// - TestRenderHtml_Basic: RenderHtml loads and executes an html template into the writer.
+// - TestRenderText_Basic: RenderText loads and executes a text template into the writer.
+// - TestRenderFrame_Basic: RenderFrame executes the page entry point of a frame-composed template.
+// - TestRender_Errors: the Render helpers report not-found, parse, execute and invalid engine failures as distinct kinds.
+// - TestRender_Buffered: a template failing late leaves the writer untouched unless Streaming is used.
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func newMapFSEngine(files map[string]string) (*Engine, fstest.MapFS) {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	e := New()
+	e.SetTemplateFS(fsys)
+	return e, fsys
+}
+
+func TestRenderHtml_Basic(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/hello.tmpl": "<p>{{.}}</p>"})
+
+	var buf bytes.Buffer
+	if err := e.RenderHtml(&buf, "hello", "en", "a&b"); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if got, want := buf.String(), "<p>a&amp;b</p>"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestRenderText_Basic(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/hello.tmpl": "<p>{{.}}</p>"})
+
+	var buf bytes.Buffer
+	if err := e.RenderText(&buf, "hello", "en", "a&b"); err != nil {
+		t.Fatalf("RenderText: %v", err)
+	}
+	if got, want := buf.String(), "<p>a&b</p>"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestRenderFrame_Basic(t *testing.T) {
+	files := map[string]string{"default/page.tmpl": "page->{{template \"_main.tmpl\" .}}"}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = frame
+	}
+	files["default/_main.tmpl"] = "main:{{.}}"
+	e, _ := newMapFSEngine(files)
+
+	var buf bytes.Buffer
+	if err := e.RenderFrame(&buf, "page", "en", "x"); err != nil {
+		t.Fatalf("RenderFrame: %v", err)
+	}
+	if got, want := buf.String(), "page->main:x"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/broken.tmpl": "{{if}}",
+		"default/fail.tmpl":   "{{.Field}}",
+	})
+
+	cases := []struct {
+		name string
+		kind error
+	}{
+		{"missing", ErrTemplateNotFound},
+		{"broken", ErrTemplateParse},
+		{"fail", ErrTemplateExecute},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		err := e.RenderHtml(&buf, c.name, "en", "x")
+		if !errors.Is(err, c.kind) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.kind, err)
+		}
+		var renderErr *RenderError
+		if !errors.As(err, &renderErr) || renderErr.Name != c.name || renderErr.Lang != "en" {
+			t.Fatalf("%s: unexpected error value %#v", c.name, err)
+		}
+	}
+
+	var nilEngine *Engine
+	err := nilEngine.RenderHtml(&bytes.Buffer{}, "hello", "en", nil)
+	if !errors.Is(err, ErrInvalidEngine) || errors.Is(err, ErrTemplateParse) {
+		t.Fatalf("nil engine: expected ErrInvalidEngine only, got %v", err)
+	}
+}
+
+func TestRender_Buffered(t *testing.T) {
+	files := map[string]string{"default/late.tmpl": "<p>head</p>{{.Field}}"}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = ""
+	}
+	e, _ := newMapFSEngine(files)
+
+	renders := map[string]func(*bytes.Buffer, ...RenderOption) error{
+		"html": func(buf *bytes.Buffer, opts ...RenderOption) error {
+			return e.RenderHtml(buf, "late", "en", "x", opts...)
+		},
+		"text": func(buf *bytes.Buffer, opts ...RenderOption) error {
+			return e.RenderText(buf, "late", "en", "x", opts...)
+		},
+		"frame": func(buf *bytes.Buffer, opts ...RenderOption) error {
+			return e.RenderFrame(buf, "late", "en", "x", opts...)
+		},
+	}
+	for name, render := range renders {
+		var buf bytes.Buffer
+		if err := render(&buf); !errors.Is(err, ErrTemplateExecute) {
+			t.Fatalf("%s: expected execute error, got %v", name, err)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("%s: partial output written: %q", name, buf.String())
+		}
+
+		buf.Reset()
+		if err := render(&buf, Streaming()); !errors.Is(err, ErrTemplateExecute) {
+			t.Fatalf("%s: expected streaming execute error, got %v", name, err)
+		}
+		if got, want := buf.String(), "<p>head</p>"; got != want {
+			t.Fatalf("%s: streaming output mismatch: got %q want %q", name, got, want)
+		}
+	}
+}
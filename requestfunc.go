@@ -0,0 +1,110 @@
+package kktemplate
+
+import (
+	"fmt"
+)
+
+var ErrRequestFuncsNotDeclared = fmt.Errorf("no request functions declared")
+
+// RenderOption adjusts a single call of the Render helpers.
+type RenderOption func(*renderConfig)
+
+type renderConfig struct {
+	funcs     map[string]any
+	streaming bool
+	inlineCSS bool
+	// emailFrames is the frame set of EmailFrames, nil when not given.
+	emailFrames *string
+}
+
+func newRenderConfig(opts []RenderOption) *renderConfig {
+	config := &renderConfig{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(config)
+		}
+	}
+	return config
+}
+
+// WithFuncs renders with request scoped function overrides, e.g. the CSRF
+// field or the current user. Every partial sees the overrides; renders use
+// copies kept with the cached template, so the overrides cost neither a clone
+// nor a reparse per call. Overridden names must be declared with
+// DeclareRequestFunc.
+func WithFuncs(funcs map[string]any) RenderOption {
+	return func(config *renderConfig) {
+		if config.funcs == nil {
+			config.funcs = map[string]any{}
+		}
+		for name, fn := range funcs {
+			config.funcs[name] = fn
+		}
+	}
+}
+
+// Streaming makes a render write to its writer as the template executes
+// instead of buffering the whole output. It saves the memory of very large
+// pages, but an execute error then leaves the output written so far in the
+// writer, e.g. half a page already sent with a 200 status.
+func Streaming() RenderOption {
+	return func(config *renderConfig) {
+		config.streaming = true
+	}
+}
+
+func DeclareRequestFunc(name string, placeholder any) {
+	defaultEngine.DeclareRequestFunc(name, placeholder)
+}
+
+// DeclareRequestFunc makes name callable from every template of the engine.
+// placeholder is used when a render does not override name through WithFuncs
+// and fixes the function signature. Cached templates are dropped so they are
+// parsed again with the new function.
+func (e *Engine) DeclareRequestFunc(name string, placeholder any) {
+	if e == nil {
+		return
+	}
+	e.factoryLocker.Lock()
+	if e.requestFuncs == nil {
+		e.requestFuncs = map[string]any{}
+	}
+	e.requestFuncs[name] = placeholder
+	e.factoryLocker.Unlock()
+	e.InvalidateAll()
+}
+
+func (e *Engine) requestFuncPlaceholders() map[string]any {
+	e.factoryLocker.RLock()
+	defer e.factoryLocker.RUnlock()
+	funcs := make(map[string]any, len(e.requestFuncs))
+	for name, fn := range e.requestFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// withRequestFuncs returns tmpl itself when config has no overrides.
+// Otherwise it returns a copy of tmpl bound to the overrides and the func that
+// gives the copy back. Errors are *RenderError for name and lang.
+func withRequestFuncs[T any](e *Engine, name string, lang string, tmpl T, copies *templateCopies[T], config *renderConfig) (T, func(), error) {
+	if len(config.funcs) == 0 {
+		return tmpl, func() {}, nil
+	}
+
+	placeholders := e.requestFuncPlaceholders()
+	reset := map[string]any{}
+	for fn := range config.funcs {
+		placeholder, ok := placeholders[fn]
+		if !ok {
+			return tmpl, nil, newExecuteError(name, lang, fmt.Errorf("%q: %w", fn, ErrRequestFuncsNotDeclared))
+		}
+		reset[fn] = placeholder
+	}
+
+	bound, err := copies.get(0, config.funcs, reset)
+	if err != nil {
+		return tmpl, nil, newLoadError(name, lang, err)
+	}
+	return bound.tmpl, func() { copies.put(bound) }, nil
+}
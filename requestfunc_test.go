@@ -0,0 +1,150 @@
+// requestfunc_test.go contains unit tests for request-scoped template functions.
+//
+// Test Case Index:
+// - TestWithFuncs_FramePartials: overrides reach partials of a cached frame page and placeholders apply otherwise.
+// - TestWithFuncs_Concurrent: concurrent renders of one cached template keep their own overrides.
+// - TestWithFuncs_Text: text templates render with overrides.
+// - TestWithFuncs_Invalidated: overrides render the cached version, survive a cache drop between load and render, fail once the file is gone, and work in debug mode.
+// - TestWithFuncs_NotDeclared: overriding without a declared request function is an execute error.
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	html "html/template"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithFuncs_FramePartials(t *testing.T) {
+	files := map[string]string{"default/page.tmpl": "{{template \"_main.tmpl\" .}}|{{csrfField}}"}
+	for _, frame := range StructTemplateFrames {
+		files["default/"+frame+".tmpl"] = frame
+	}
+	files["default/_main.tmpl"] = "<form>{{csrfField}}</form>"
+	e, _ := newMapFSEngine(files)
+	e.DeclareRequestFunc("csrfField", func() html.HTML { return "" })
+
+	var buf bytes.Buffer
+	field := func() html.HTML { return `<input name="csrf" value="t1">` }
+	if err := e.RenderFrame(&buf, "page", "en", nil, WithFuncs(map[string]any{"csrfField": field})); err != nil {
+		t.Fatalf("RenderFrame: %v", err)
+	}
+	want := `<form><input name="csrf" value="t1"></form>|<input name="csrf" value="t1">`
+	if got := buf.String(); got != want {
+		t.Fatalf("RenderFrame: got %q want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := e.RenderFrame(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderFrame without overrides: %v", err)
+	}
+	if got, want := buf.String(), "<form></form>|"; got != want {
+		t.Fatalf("RenderFrame without overrides: got %q want %q", got, want)
+	}
+}
+
+func TestWithFuncs_Concurrent(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{currentUser}}"})
+	e.DeclareRequestFunc("currentUser", func() string { return "" })
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := "user" + strconv.Itoa(i)
+			var buf bytes.Buffer
+			if err := e.RenderHtml(&buf, "page", "en", nil, WithFuncs(map[string]any{"currentUser": func() string { return user }})); err != nil {
+				errs <- err
+				return
+			}
+			if buf.String() != user {
+				errs <- errors.New("got " + buf.String() + " want " + user)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestWithFuncs_Text(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/mail.tmpl": "Hi {{currentUser}}"})
+	e.DeclareRequestFunc("currentUser", func() string { return "guest" })
+
+	var buf bytes.Buffer
+	if err := e.RenderText(&buf, "mail", "en", nil, WithFuncs(map[string]any{"currentUser": func() string { return "<ann>" }})); err != nil {
+		t.Fatalf("RenderText: %v", err)
+	}
+	if got, want := buf.String(), "Hi <ann>"; got != want {
+		t.Fatalf("RenderText: got %q want %q", got, want)
+	}
+}
+
+func TestWithFuncs_Invalidated(t *testing.T) {
+	e, fsys := newMapFSEngine(map[string]string{"default/page.tmpl": "v1 {{currentUser}}"})
+	e.DeclareRequestFunc("currentUser", func() string { return "" })
+	withUser := func(user string) RenderOption {
+		return WithFuncs(map[string]any{"currentUser": func() string { return user }})
+	}
+
+	var buf bytes.Buffer
+	if err := e.RenderHtml(&buf, "page", "en", nil); err != nil || buf.String() != "v1 " {
+		t.Fatalf("RenderHtml: got %q, %v", buf.String(), err)
+	}
+	fsys["default/page.tmpl"] = &fstest.MapFile{Data: []byte("v2 {{currentUser}}")}
+	buf.Reset()
+	if err := e.RenderHtml(&buf, "page", "en", nil, withUser("ann")); err != nil || buf.String() != "v1 ann" {
+		t.Fatalf("RenderHtml with overrides: got %q, %v, want the cached version", buf.String(), err)
+	}
+
+	tmpl, copies, err := e.loadHtml("page", "en")
+	if err != nil {
+		t.Fatalf("loadHtml: %v", err)
+	}
+	e.InvalidateAll()
+	bound, release, err := withRequestFuncs(e, "page", "en", tmpl, copies, newRenderConfig([]RenderOption{withUser("bob")}))
+	if err != nil {
+		t.Fatalf("withRequestFuncs after InvalidateAll: %v", err)
+	}
+	buf.Reset()
+	if err := bound.Execute(&buf, nil); err != nil || buf.String() != "v1 bob" {
+		t.Fatalf("Execute: got %q, %v", buf.String(), err)
+	}
+	release()
+
+	delete(fsys, "default/page.tmpl")
+	e.Invalidate("page", "")
+	if err := e.RenderHtml(io.Discard, "page", "en", nil, withUser("cy")); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected ErrTemplateNotFound after the file is deleted, got %v", err)
+	}
+
+	fsys["default/page.tmpl"] = &fstest.MapFile{Data: []byte("v3 {{currentUser}}")}
+	e.SetDebug(true)
+	for _, user := range []string{"dan", "eve"} {
+		buf.Reset()
+		if err := e.RenderHtml(&buf, "page", "en", nil, withUser(user)); err != nil {
+			t.Fatalf("RenderHtml in debug mode: %v", err)
+		}
+		if want := "v3 " + user; buf.String() != want {
+			t.Fatalf("debug mode: got %q want %q", buf.String(), want)
+		}
+	}
+}
+
+func TestWithFuncs_NotDeclared(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "page"})
+
+	var buf bytes.Buffer
+	err := e.RenderHtml(&buf, "page", "en", nil, WithFuncs(map[string]any{"nonce": func() string { return "n" }}))
+	if !errors.Is(err, ErrTemplateExecute) || !errors.Is(err, ErrRequestFuncsNotDeclared) {
+		t.Fatalf("expected ErrRequestFuncsNotDeclared execute error, got %v", err)
+	}
+}
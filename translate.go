@@ -0,0 +1,224 @@
+package kktemplate
+
+import (
+	"fmt"
+	html "html/template"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/yetiz-org/goth-kktranslation"
+)
+
+// translate returns the translation of key for lang and whether the
+// dictionaries have one.
+func translate(lang string, key string) (string, bool) {
+	message := kktranslation.GetLangFile(lang).T(key)
+	return message, message != key
+}
+
+// translateFormat implements Tf: the translation of key with {0}, {1}, ...
+// replaced by args and {name} replaced from a map argument.
+func translateFormat(lang string, key string, args []any, escape bool) string {
+	message, _ := translate(lang, key)
+	return interpolate(message, args, escape)
+}
+
+// translatePlural implements Tn: the translation of key.<category>, where the
+// category is the CLDR plural category of count in lang, falling back to
+// key.other and then key. {count} and {0} are replaced by count.
+func translatePlural(lang string, key string, count any, args []any, escape bool) string {
+	message, ok := translate(lang, key+"."+PluralCategory(lang, count))
+	if !ok {
+		if message, ok = translate(lang, key+".other"); !ok {
+			message, _ = translate(lang, key)
+		}
+	}
+
+	named := map[string]any{"count": count}
+	for _, arg := range args {
+		if m, ok := arg.(map[string]any); ok {
+			for k, v := range m {
+				named[k] = v
+			}
+		}
+	}
+	return interpolate(message, append([]any{count, named}, args...), escape)
+}
+
+// interpolate replaces {N} by the N-th positional argument and {name} by the
+// value of name in any map[string]any argument. Map arguments do not count
+// as positional. Unknown placeholders are left untouched. With escape, values
+// other than html.HTML are HTML escaped; the message itself never is.
+func interpolate(message string, args []any, escape bool) string {
+	if !strings.Contains(message, "{") {
+		return message
+	}
+
+	var positional []any
+	named := map[string]any{}
+	for _, arg := range args {
+		if m, ok := arg.(map[string]any); ok {
+			for k, v := range m {
+				named[k] = v
+			}
+			continue
+		}
+		positional = append(positional, arg)
+	}
+
+	format := func(v any) string {
+		if h, ok := v.(html.HTML); ok && escape {
+			return string(h)
+		}
+		s := fmt.Sprint(v)
+		if escape {
+			return html.HTMLEscapeString(s)
+		}
+		return s
+	}
+
+	var out strings.Builder
+	for {
+		start := strings.Index(message, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(message[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+
+		placeholder := message[start+1 : end]
+		out.WriteString(message[:start])
+		if i, err := strconv.Atoi(placeholder); err == nil && i >= 0 && i < len(positional) {
+			out.WriteString(format(positional[i]))
+		} else if v, ok := named[placeholder]; ok {
+			out.WriteString(format(v))
+		} else {
+			out.WriteString(message[start : end+1])
+		}
+		message = message[end+1:]
+	}
+	out.WriteString(message)
+	return out.String()
+}
+
+// PluralCategory returns the CLDR plural category (zero, one, two, few, many
+// or other) of count for the base language of lang. Non numeric counts and
+// unknown languages yield the English rules.
+func PluralCategory(lang string, count any) string {
+	n, ok := toFloat(count)
+	if !ok {
+		return "other"
+	}
+
+	base := strings.ToLower(strings.SplitN(strings.ReplaceAll(lang, "_", "-"), "-", 2)[0])
+	n = math.Abs(n)
+	integer := n == math.Trunc(n)
+	i := int64(n)
+	mod10, mod100 := i%10, i%100
+
+	switch base {
+	case "ja", "zh", "ko", "th", "vi", "id", "ms", "lo", "my", "km":
+		return "other"
+	case "fr":
+		if i == 0 || i == 1 {
+			return "one"
+		}
+		return "other"
+	case "pt":
+		if strings.EqualFold(lang, "pt-PT") {
+			if n == 1 {
+				return "one"
+			}
+			return "other"
+		}
+		if i == 0 || i == 1 {
+			return "one"
+		}
+		return "other"
+	case "ru", "uk", "be":
+		switch {
+		case !integer:
+			return "other"
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "pl":
+		switch {
+		case !integer:
+			return "other"
+		case i == 1:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "cs", "sk":
+		switch {
+		case !integer:
+			return "many"
+		case i == 1:
+			return "one"
+		case i >= 2 && i <= 4:
+			return "few"
+		default:
+			return "other"
+		}
+	case "ar":
+		switch {
+		case !integer:
+			return "other"
+		case i == 0:
+			return "zero"
+		case i == 1:
+			return "one"
+		case i == 2:
+			return "two"
+		case mod100 >= 3 && mod100 <= 10:
+			return "few"
+		case mod100 >= 11:
+			return "many"
+		default:
+			return "other"
+		}
+	case "he":
+		switch {
+		case integer && i == 1:
+			return "one"
+		case integer && i == 2:
+			return "two"
+		default:
+			return "other"
+		}
+	}
+
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(rv.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,127 @@
+// translate_test.go contains unit tests for the Tf and Tn translation functions.
+//
+// Test Case Index:
+// - TestPluralCategory: CLDR plural categories are chosen per base language.
+// - TestInterpolate: positional and named placeholders are replaced, unknown ones kept.
+// - TestTf_Html_EscapesArguments: the html Tf escapes arguments but keeps translator markup.
+// - TestTn_Text: the text Tn picks the plural form for the language and falls back to key.other.
+// - TestCheckTranslations_Plural: plural dictionary entries satisfy Tn keys and count as used.
+package kktemplate
+
+import (
+	"bytes"
+	html "html/template"
+	"testing"
+
+	"github.com/yetiz-org/goth-kktranslation"
+)
+
+func TestPluralCategory(t *testing.T) {
+	cases := []struct {
+		lang  string
+		count any
+		want  string
+	}{
+		{"en", 1, "one"},
+		{"en-US", 2, "other"},
+		{"en", 1.5, "other"},
+		{"fr", 0, "one"},
+		{"pt-BR", 0, "one"},
+		{"pt-PT", 0, "other"},
+		{"zh-TW", 1, "other"},
+		{"ru", 21, "one"},
+		{"ru", 23, "few"},
+		{"ru", 12, "many"},
+		{"pl", 5, "many"},
+		{"cs", 3, "few"},
+		{"ar", 0, "zero"},
+		{"ar", 2, "two"},
+		{"ar", 105, "few"},
+		{"ar", 111, "many"},
+		{"en", "1", "one"},
+		{"en", "x", "other"},
+	}
+	for _, c := range cases {
+		if got := PluralCategory(c.lang, c.count); got != c.want {
+			t.Fatalf("PluralCategory(%s, %v): got %q want %q", c.lang, c.count, got, c.want)
+		}
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	got := interpolate("{0} and {name}, {1} {missing} {", []any{"a", map[string]any{"name": "<b>"}, 2}, false)
+	if want := "a and <b>, 2 {missing} {"; got != want {
+		t.Fatalf("interpolate: got %q want %q", got, want)
+	}
+	got = interpolate("<em>{0}</em>{1}", []any{"<b>", html.HTML("<i>")}, true)
+	if want := "<em>&lt;b&gt;</em><i>"; got != want {
+		t.Fatalf("interpolate escaped: got %q want %q", got, want)
+	}
+}
+
+func writeTranslateFixture(t *testing.T) {
+	t.Helper()
+	translationRoot := withTempTranslationRoot(t)
+	resetTranslationGlobals(t, translationRoot, true, "default")
+	t.Setenv("KKAPP_DEBUG", "TRUE")
+	writeTranslationFile(t, translationRoot, "en", "version: \"1\"\nlang: \"en\"\nname: \"English\"\ndict:\n"+
+		"  greet: \"Hello <b>{name}</b>, you are {0}\"\n"+
+		"  items.one: \"{count} item\"\n"+
+		"  items.other: \"{count} items for {1}\"\n")
+	writeTranslationFile(t, translationRoot, "ru", "version: \"1\"\nlang: \"ru\"\nname: \"Russian\"\ndict:\n"+
+		"  items.few: \"{count} предмета\"\n"+
+		"  items.other: \"{count} предметов\"\n")
+}
+
+func TestTf_Html_EscapesArguments(t *testing.T) {
+	writeTranslateFixture(t)
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{Tf \"greet\" .Age .}}"})
+
+	var buf bytes.Buffer
+	data := map[string]any{"name": "<script>", "Age": 3}
+	if err := e.RenderHtml(&buf, "page", "en", data); err != nil {
+		t.Fatalf("RenderHtml: %v", err)
+	}
+	if got, want := buf.String(), "Hello <b>&lt;script&gt;</b>, you are 3"; got != want {
+		t.Fatalf("output mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestTn_Text(t *testing.T) {
+	writeTranslateFixture(t)
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{Tn \"items\" .N \"cart\"}}"})
+
+	cases := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 1, "1 item"},
+		{"en", 5, "5 items for cart"},
+		{"ru", 3, "3 предмета"},
+		{"ru", 5, "5 предметов"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := e.RenderText(&buf, "page", c.lang, map[string]any{"N": c.n}); err != nil {
+			t.Fatalf("RenderText: %v", err)
+		}
+		if got := buf.String(); got != c.want {
+			t.Fatalf("Tn(%s, %d): got %q want %q", c.lang, c.n, got, c.want)
+		}
+	}
+}
+
+func TestCheckTranslations_Plural(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{Tn \"items\" 2}}{{Tf \"greet\" 1}}"})
+
+	reports, err := e.CheckTranslations(kktranslation.LangFile{Lang: "en", Dict: map[string]string{
+		"items.one": "", "items.other": "", "greet": "", "items.unrelated": "",
+	}})
+	if err != nil {
+		t.Fatalf("CheckTranslations: %v", err)
+	}
+	if len(reports[0].Missing) != 0 || len(reports[0].Unused) != 1 || reports[0].Unused[0] != "items.unrelated" {
+		t.Fatalf("unexpected report: %+v", reports[0])
+	}
+}
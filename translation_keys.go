@@ -0,0 +1,203 @@
+package kktemplate
+
+import (
+	"io/fs"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/yetiz-org/goth-kktranslation"
+	"gopkg.in/yaml.v2"
+)
+
+// translationFuncs are the template functions whose first argument is a
+// translation key. Keys of Tn are reported without their plural suffix.
+var translationFuncs = map[string]bool{"T": true, "Tf": true, "Tn": true}
+
+// TranslationKey is a literal key passed to a translation function. Plural
+// keys come from Tn and are looked up as "<key>.<plural category>".
+type TranslationKey struct {
+	Key    string `json:"key"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Plural bool   `json:"plural,omitempty"`
+}
+
+// TranslationReport compares the keys used by the templates with one
+// kktranslation dictionary.
+type TranslationReport struct {
+	Lang    string           `json:"lang"`
+	Missing []TranslationKey `json:"missing"`
+	Unused  []string         `json:"unused"`
+}
+
+func TranslationKeys() ([]TranslationKey, error) {
+	return defaultEngine.TranslationKeys()
+}
+
+// TranslationKeys statically collects every literal key passed to T in the
+// template tree, sorted by key, file and line. Keys computed at run time
+// cannot be found this way and are skipped.
+func (e *Engine) TranslationKeys() ([]TranslationKey, error) {
+	if e == nil {
+		return nil, ErrInvalidEngine
+	}
+
+	dirs, _, err := e.templateTree()
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := e.sourceFS()
+	var keys []TranslationKey
+	for _, dir := range dirs {
+		files := e.langTemplates(fsys, dir)
+		for _, name := range sortedKeys(files) {
+			data, err := fs.ReadFile(fsys, files[name])
+			if err != nil {
+				return nil, err
+			}
+			trees, err := parseLintTrees(files[name], string(data))
+			if err != nil {
+				return nil, err
+			}
+			for _, tree := range trees {
+				keys = append(keys, treeTranslationKeys(tree, files[name])...)
+			}
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].Key != keys[j].Key {
+			return keys[i].Key < keys[j].Key
+		}
+		if keys[i].File != keys[j].File {
+			return keys[i].File < keys[j].File
+		}
+		return keys[i].Line < keys[j].Line
+	})
+	return keys, nil
+}
+
+// treeTranslationKeys finds both {{T "key"}} and {{"key" | T}}.
+func treeTranslationKeys(tree *parse.Tree, file string) []TranslationKey {
+	var keys []TranslationKey
+	add := func(fn parse.Node, node *parse.StringNode) {
+		plural := fn.(*parse.IdentifierNode).Ident == "Tn"
+		keys = append(keys, TranslationKey{Key: node.Text, File: file, Line: nodeLine(tree, node), Plural: plural})
+	}
+
+	walkTemplateNodes(tree.Root, func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.CommandNode:
+			if len(n.Args) < 2 || !isTranslationFunc(n.Args[0]) {
+				return
+			}
+			if key, ok := n.Args[1].(*parse.StringNode); ok {
+				add(n.Args[0], key)
+			}
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for i := 1; i < len(n.Cmds); i++ {
+				if len(n.Cmds[i].Args) != 1 || !isTranslationFunc(n.Cmds[i].Args[0]) || len(n.Cmds[i-1].Args) != 1 {
+					continue
+				}
+				if key, ok := n.Cmds[i-1].Args[0].(*parse.StringNode); ok {
+					add(n.Cmds[i].Args[0], key)
+				}
+			}
+		}
+	})
+	return keys
+}
+
+func isTranslationFunc(node parse.Node) bool {
+	ident, ok := node.(*parse.IdentifierNode)
+	return ok && translationFuncs[ident.Ident]
+}
+
+func CheckTranslations(langFiles ...kktranslation.LangFile) ([]TranslationReport, error) {
+	return defaultEngine.CheckTranslations(langFiles...)
+}
+
+// CheckTranslations reports, for each dictionary, the template keys it lacks
+// and the dictionary keys no template uses. Without arguments it checks
+// kktranslation.LangFiles().
+func (e *Engine) CheckTranslations(langFiles ...kktranslation.LangFile) ([]TranslationReport, error) {
+	keys, err := e.TranslationKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(langFiles) == 0 {
+		langFiles = kktranslation.LangFiles()
+	}
+
+	used, plurals := map[string]bool{}, map[string]bool{}
+	for _, key := range keys {
+		used[key.Key] = true
+		if key.Plural {
+			plurals[key.Key] = true
+		}
+	}
+
+	reports := make([]TranslationReport, 0, len(langFiles))
+	for _, langFile := range langFiles {
+		report := TranslationReport{Lang: langFile.Lang, Missing: []TranslationKey{}, Unused: []string{}}
+		for _, key := range keys {
+			if !dictHasKey(langFile.Dict, key) {
+				report.Missing = append(report.Missing, key)
+			}
+		}
+		for _, key := range sortedKeys(langFile.Dict) {
+			if used[key] {
+				continue
+			}
+			if i := strings.LastIndex(key, "."); i > 0 && plurals[key[:i]] && pluralCategories[key[i+1:]] {
+				continue
+			}
+			report.Unused = append(report.Unused, key)
+		}
+		reports = append(reports, report)
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].Lang < reports[j].Lang })
+	return reports, nil
+}
+
+var pluralCategories = map[string]bool{"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true}
+
+func dictHasKey(dict map[string]string, key TranslationKey) bool {
+	if _, ok := dict[key.Key]; ok {
+		return true
+	}
+	if key.Plural {
+		for category := range pluralCategories {
+			if _, ok := dict[key.Key+"."+category]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TranslationStub renders keys as a kktranslation YAML file for lang, with
+// every key translated to itself, ready to be filled in. Plural keys are
+// written as "<key>.other".
+func TranslationStub(lang string, keys []TranslationKey) ([]byte, error) {
+	dict := map[string]string{}
+	for _, key := range keys {
+		if key.Plural {
+			dict[key.Key+".other"] = key.Key
+			continue
+		}
+		dict[key.Key] = key.Key
+	}
+	return yaml.Marshal(kktranslation.LangFile{
+		Version: "1",
+		Lang:    strings.ToLower(lang),
+		Name:    lang,
+		Dict:    dict,
+	})
+}
@@ -0,0 +1,90 @@
+// translation_keys_test.go contains unit tests for translation key extraction.
+//
+// Test Case Index:
+// - TestTranslationKeys: literal keys of T calls and T pipelines are collected with file and line.
+// - TestCheckTranslations: missing and unused keys are reported per dictionary.
+// - TestCheckTranslations_LangFiles: without arguments the kktranslation dictionaries on disk are used.
+// - TestTranslationStub: the stub is a kktranslation YAML file holding the given keys.
+package kktemplate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yetiz-org/goth-kktranslation"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTranslationKeys(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{
+		"default/page.tmpl": "{{T \"title\"}}\n{{\"intro\" | T}}\n{{define \"x\"}}{{if .}}{{T \"nested\"}}{{end}}{{end}}{{T .Dynamic}}",
+		"en/page.tmpl":      "{{T \"title\"}}",
+	})
+
+	keys, err := e.TranslationKeys()
+	if err != nil {
+		t.Fatalf("TranslationKeys: %v", err)
+	}
+	want := []TranslationKey{
+		{Key: "intro", File: "default/page.tmpl", Line: 2},
+		{Key: "nested", File: "default/page.tmpl", Line: 3},
+		{Key: "title", File: "default/page.tmpl", Line: 1},
+		{Key: "title", File: "en/page.tmpl", Line: 1},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys mismatch:\ngot  %v\nwant %v", keys, want)
+	}
+}
+
+func TestCheckTranslations(t *testing.T) {
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{T \"title\"}}{{T \"intro\"}}"})
+
+	reports, err := e.CheckTranslations(
+		kktranslation.LangFile{Lang: "ja", Dict: map[string]string{"title": "タイトル", "old": "old"}},
+		kktranslation.LangFile{Lang: "en", Dict: map[string]string{"title": "Title", "intro": "Intro"}},
+	)
+	if err != nil {
+		t.Fatalf("CheckTranslations: %v", err)
+	}
+	want := []TranslationReport{
+		{Lang: "en", Missing: []TranslationKey{}, Unused: []string{}},
+		{Lang: "ja", Missing: []TranslationKey{{Key: "intro", File: "default/page.tmpl", Line: 1}}, Unused: []string{"old"}},
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Fatalf("reports mismatch:\ngot  %+v\nwant %+v", reports, want)
+	}
+}
+
+func TestCheckTranslations_LangFiles(t *testing.T) {
+	translationRoot := withTempTranslationRoot(t)
+	resetTranslationGlobals(t, translationRoot, true, "default")
+	t.Setenv("KKAPP_DEBUG", "TRUE")
+	writeTranslationFile(t, translationRoot, "en", "version: \"1\"\nlang: \"en\"\nname: \"English\"\ndict:\n  hello: \"HELLO\"\n")
+
+	e, _ := newMapFSEngine(map[string]string{"default/page.tmpl": "{{T \"bye\"}}"})
+	reports, err := e.CheckTranslations()
+	if err != nil {
+		t.Fatalf("CheckTranslations: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Lang != "en" || len(reports[0].Missing) != 1 || !reflect.DeepEqual(reports[0].Unused, []string{"hello"}) {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+}
+
+func TestTranslationStub(t *testing.T) {
+	data, err := TranslationStub("zh-TW", []TranslationKey{{Key: "title"}, {Key: "intro"}, {Key: "title"}})
+	if err != nil {
+		t.Fatalf("TranslationStub: %v", err)
+	}
+
+	var langFile kktranslation.LangFile
+	if err := yaml.Unmarshal(data, &langFile); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if langFile.Lang != "zh-tw" || langFile.Version != "1" {
+		t.Fatalf("unexpected header: %+v", langFile)
+	}
+	if want := map[string]string{"title": "title", "intro": "intro"}; !reflect.DeepEqual(langFile.Dict, want) {
+		t.Fatalf("dict mismatch: got %v want %v", langFile.Dict, want)
+	}
+}
@@ -0,0 +1,205 @@
+package kktemplate
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrWatcherRunning = fmt.Errorf("template watcher already running")
+
+// WatchOptions configures Engine.Watch.
+type WatchOptions struct {
+	// Interval is the scan period of the polling watcher. Default 1s.
+	Interval time.Duration
+	// Polling forces the polling watcher even where native file system
+	// notifications are available.
+	Polling bool
+	// OnError receives errors raised while reloading changed templates and
+	// errors of the watcher itself. It is called from the watcher goroutine.
+	OnError func(error)
+}
+
+type templateWatcher struct {
+	stop    chan struct{}
+	done    chan struct{}
+	onError func(error)
+	close   func()
+}
+
+// Watch starts watching the template source and invalidates only the cached
+// templates built from changed files; when a frame file changes, every
+// frame-composed page is invalidated as well. The invalidated templates are
+// parsed again immediately and failures are reported through
+// opts.OnError. Native notifications are used for a local template root
+// where supported, otherwise the source is polled. Watch returns
+// ErrWatcherRunning if the engine is already watching.
+func (e *Engine) Watch(opts WatchOptions) error {
+	if e == nil {
+		return ErrInvalidEngine
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+
+	e.watchLocker.Lock()
+	defer e.watchLocker.Unlock()
+	if e.watcher != nil {
+		return ErrWatcherRunning
+	}
+
+	w := &templateWatcher{
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		onError: opts.OnError,
+	}
+	if opts.Polling || e.templateFSValue() != nil || !e.startNativeWatch(w) {
+		e.startPollingWatch(w, opts.Interval)
+	}
+	e.watcher = w
+	return nil
+}
+
+// StopWatch stops a watcher started by Watch and waits for it to exit.
+func (e *Engine) StopWatch() {
+	if e == nil {
+		return
+	}
+	e.watchLocker.Lock()
+	w := e.watcher
+	e.watcher = nil
+	e.watchLocker.Unlock()
+	if w == nil {
+		return
+	}
+
+	close(w.stop)
+	if w.close != nil {
+		w.close()
+	}
+	<-w.done
+}
+
+func (w *templateWatcher) report(err error) {
+	if err != nil && w.onError != nil {
+		w.onError(err)
+	}
+}
+
+// templatesChanged handles a batch of changed paths relative to the template
+// root.
+func (e *Engine) templatesChanged(w *templateWatcher, paths []string) {
+	names := map[string]bool{}
+	for _, p := range paths {
+		if name, ok := templateNameOf(p); ok {
+			names[name] = true
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	e.resetFrameChecks(names)
+	for _, entry := range e.invalidateNames(names) {
+		w.report(e.reloadCache(entry))
+	}
+}
+
+// templateNameOf maps "<lang>/<name>.tmpl" to the logical template name.
+func templateNameOf(p string) (string, bool) {
+	p = path.Clean(strings.TrimPrefix(p, "./"))
+	if !strings.HasSuffix(p, ".tmpl") {
+		return "", false
+	}
+	p = strings.TrimSuffix(p, ".tmpl")
+	if i := strings.Index(p, "/"); i >= 0 {
+		return p[i+1:], true
+	}
+	return p, true
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (e *Engine) snapshotTemplates() map[string]fileStamp {
+	snapshot := map[string]fileStamp{}
+	_ = fs.WalkDir(e.sourceFS(), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".tmpl") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			snapshot[p] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return snapshot
+}
+
+func (e *Engine) startPollingWatch(w *templateWatcher, interval time.Duration) {
+	previous := e.snapshotTemplates()
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+
+			current := e.snapshotTemplates()
+			var changed []string
+			for p, stamp := range current {
+				if old, ok := previous[p]; !ok || old != stamp {
+					changed = append(changed, p)
+				}
+			}
+			for p := range previous {
+				if _, ok := current[p]; !ok {
+					changed = append(changed, p)
+				}
+			}
+			previous = current
+			e.templatesChanged(w, changed)
+		}
+	}()
+}
+
+// watchBatch collects paths from events and flushes them once the stream
+// has been quiet for a moment, so an editor's write-rename sequence causes
+// a single reload.
+type watchBatch struct {
+	mu    sync.Mutex
+	paths []string
+	timer *time.Timer
+}
+
+func (b *watchBatch) add(p string, flush func([]string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.paths = append(b.paths, p)
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.timer = time.AfterFunc(50*time.Millisecond, func() {
+		b.mu.Lock()
+		paths := b.paths
+		b.paths = nil
+		b.mu.Unlock()
+		flush(paths)
+	})
+}
+
+func (b *watchBatch) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+}
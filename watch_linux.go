@@ -0,0 +1,128 @@
+//go:build linux
+
+package kktemplate
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+// inotifyWatcher watches every directory below root; inotify is not
+// recursive, so directories created later are added as they appear.
+type inotifyWatcher struct {
+	root string
+	fd   int
+	file *os.File
+	dirs map[int32]string
+}
+
+func (e *Engine) startNativeWatch(w *templateWatcher) bool {
+	root := e.templateRootPathValue()
+	if root == "" {
+		root = "."
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return false
+	}
+	n := &inotifyWatcher{root: root, fd: fd, file: os.NewFile(uintptr(fd), "inotify"), dirs: map[int32]string{}}
+	if _, err := n.addTree(root); err != nil {
+		n.file.Close()
+		return false
+	}
+
+	w.close = func() { n.file.Close() }
+	go n.run(e, w)
+	return true
+}
+
+// addTree watches dir and its subdirectories and returns the template files
+// already inside them.
+func (n *inotifyWatcher) addTree(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, p)
+			return nil
+		}
+		wd, err := syscall.InotifyAddWatch(n.fd, p, inotifyMask)
+		if err != nil {
+			return err
+		}
+		n.dirs[int32(wd)] = p
+		return nil
+	})
+	return files, err
+}
+
+func (n *inotifyWatcher) relative(p string) string {
+	if rel, err := filepath.Rel(n.root, p); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(p)
+}
+
+func (n *inotifyWatcher) run(e *Engine, w *templateWatcher) {
+	batch := &watchBatch{}
+	flush := func(paths []string) {
+		select {
+		case <-w.stop:
+		default:
+			e.templatesChanged(w, paths)
+		}
+	}
+	defer close(w.done)
+	defer batch.stop()
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			select {
+			case <-w.stop:
+			default:
+				w.report(err)
+			}
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= size; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			dir, ok := n.dirs[event.Wd]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(n.dirs, event.Wd)
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			p := filepath.Join(dir, strings.TrimRight(string(nameBytes), "\x00"))
+			if event.Mask&syscall.IN_ISDIR != 0 {
+				if event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+					files, err := n.addTree(p)
+					w.report(err)
+					for _, file := range files {
+						batch.add(n.relative(file), flush)
+					}
+				}
+				continue
+			}
+			batch.add(n.relative(p), flush)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package kktemplate
+
+// startNativeWatch reports that no native watcher is available, so Watch
+// falls back to polling.
+func (e *Engine) startNativeWatch(w *templateWatcher) bool {
+	return false
+}
@@ -0,0 +1,138 @@
+// watch_test.go contains unit tests for the template watcher.
+//
+// Test Case Index:
+// - TestWatch_Polling_InvalidatesChangedTemplate: a changed file reloads its own cache entry and leaves others alone.
+// - TestWatch_Native_FrameChange: changing a frame file reloads every frame-composed page.
+// - TestWatch_ReportsReloadError: a reload that fails to parse is reported through OnError.
+// - TestWatch_AlreadyRunning: a second Watch call fails with ErrWatcherRunning.
+package kktemplate
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func newWatchedEngine(t *testing.T) (*Engine, string) {
+	t.Helper()
+	root := withTempTemplateRoot(t)
+	e := New()
+	e.SetTemplateRootPath(root)
+	return e, root
+}
+
+func startWatch(t *testing.T, e *Engine, opts WatchOptions) {
+	t.Helper()
+	if err := e.Watch(opts); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	t.Cleanup(e.StopWatch)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func cachedHtml(e *Engine, mapName string) string {
+	e.htmlLocker.Lock()
+	tmpl := (*e.htmlTemplateMap)[mapName]
+	e.htmlLocker.Unlock()
+	if tmpl == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	_ = tmpl.Execute(&buf, nil)
+	return buf.String()
+}
+
+func TestWatch_Polling_InvalidatesChangedTemplate(t *testing.T) {
+	e, root := newWatchedEngine(t)
+	path := writeTemplateFile(t, root, "default", "hello", "v1")
+	writeTemplateFile(t, root, "default", "other", "other")
+
+	if _, err := e.LoadHtml("hello", "en"); err != nil {
+		t.Fatalf("LoadHtml(hello): %v", err)
+	}
+	other, err := e.LoadHtml("other", "en")
+	if err != nil {
+		t.Fatalf("LoadHtml(other): %v", err)
+	}
+
+	startWatch(t, e, WatchOptions{Polling: true, Interval: 10 * time.Millisecond})
+	if err := os.WriteFile(path, []byte("v2 changed"), 0o644); err != nil {
+		t.Fatalf("rewrite template: %v", err)
+	}
+
+	waitFor(t, "hello reload", func() bool { return cachedHtml(e, "hello-en") == "v2 changed" })
+	if again, _ := e.LoadHtml("other", "en"); again != other {
+		t.Fatalf("unrelated template was invalidated")
+	}
+}
+
+func TestWatch_Native_FrameChange(t *testing.T) {
+	e, root := newWatchedEngine(t)
+	for _, frame := range e.structTemplateFramesValue() {
+		writeTemplateFile(t, root, "default", frame, frame)
+	}
+	writeTemplateFile(t, root, "default", "page", "page->{{template \"_main.tmpl\"}}")
+
+	var buf bytes.Buffer
+	if err := e.RenderFrame(&buf, "page", "en", nil); err != nil {
+		t.Fatalf("RenderFrame: %v", err)
+	}
+
+	startWatch(t, e, WatchOptions{Interval: 10 * time.Millisecond})
+	writeTemplateFile(t, root, "en", "_main", "en main")
+
+	waitFor(t, "frame reload", func() bool {
+		e.htmlLocker.Lock()
+		tmpl := (*e.frameHtmlTemplateMap)["page-en"]
+		e.htmlLocker.Unlock()
+		if tmpl == nil {
+			return false
+		}
+		var out bytes.Buffer
+		_ = tmpl.ExecuteTemplate(&out, "page.tmpl", nil)
+		return out.String() == "page->en main"
+	})
+}
+
+func TestWatch_ReportsReloadError(t *testing.T) {
+	e, root := newWatchedEngine(t)
+	path := writeTemplateFile(t, root, "default", "hello", "v1")
+	if _, err := e.LoadText("hello", "en"); err != nil {
+		t.Fatalf("LoadText: %v", err)
+	}
+
+	errs := make(chan error, 4)
+	startWatch(t, e, WatchOptions{Polling: true, Interval: 10 * time.Millisecond, OnError: func(err error) { errs <- err }})
+	if err := os.WriteFile(path, []byte("{{if}}"), 0o644); err != nil {
+		t.Fatalf("rewrite template: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrTemplateParse) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for reload error")
+	}
+}
+
+func TestWatch_AlreadyRunning(t *testing.T) {
+	e, _ := newWatchedEngine(t)
+	startWatch(t, e, WatchOptions{Polling: true})
+	if err := e.Watch(WatchOptions{}); err != ErrWatcherRunning {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}